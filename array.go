@@ -0,0 +1,122 @@
+package sling
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+// ReceiveArray returns an iterator which sends the Sling's request and
+// decodes the elements of a JSON array in a success (2XX) response one at a
+// time into values of type T, so large arrays aren't held in memory. The
+// array may be the top-level value (path "") or nested under a dot-separated
+// path of object fields (e.g. "data.items"). A missing or null array yields
+// no elements. Non-2XX responses are decoded into the value pointed to by
+// failureV (if non-nil) with the Sling's ResponseDecoder and yielded as an
+// *HTTPError. Errors sending the request or decoding elements are yielded
+// last.
+func ReceiveArray[T any](s *Sling, path string, failureV interface{}) iter.Seq2[T, error] {
+	return receiveSeq(s, failureV, func(resp *http.Response, yield func(T) bool) error {
+		return decodeArray(resp, path, yield)
+	})
+}
+
+// ReceiveArrayFunc sends the Sling's request and calls fn with each element
+// of a JSON array in a success (2XX) response, decoded into a value of type T,
+// stopping if fn returns an error. See ReceiveArray for details. Returns the
+// error from fn or any error sending the request or decoding elements.
+func ReceiveArrayFunc[T any](s *Sling, path string, failureV interface{}, fn func(item T) error) (*http.Response, error) {
+	return receiveFunc(s, failureV, fn, func(resp *http.Response, yield func(T) bool) error {
+		return decodeArray(resp, path, yield)
+	})
+}
+
+// decodeArray walks the JSON tokens of the response Body to the array at the
+// dot-separated path and decodes its elements, calling yield with each until
+// yield returns false.
+func decodeArray[T any](resp *http.Response, path string, yield func(T) bool) error {
+	if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(resp.Body)
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			found, err := findJSONField(dec, key)
+			if err != nil {
+				return fmt.Errorf("sling: decoding field %q: %w", path, err)
+			}
+			if !found {
+				return nil
+			}
+		}
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("sling: expected JSON array at %q, got %v", path, tok)
+	}
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return err
+		}
+		if !yield(item) {
+			return nil
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// findJSONField reads tokens of a JSON object until the value of the field
+// key is next, skipping other fields. Returns false if the value is null or
+// doesn't have the field.
+func findJSONField(dec *json.Decoder, key string) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return false, err
+	}
+	if tok != json.Delim('{') {
+		return false, fmt.Errorf("expected JSON object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if tok == key {
+			return true, nil
+		}
+		if err := skipJSONValue(dec); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// skipJSONValue reads the tokens of the next JSON value without decoding it.
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
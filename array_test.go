@@ -0,0 +1,94 @@
+package sling
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReceiveArray(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/top", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"text": "a"}, {"text": "b", "favorite_count": 2}]`)
+	})
+	mux.HandleFunc("/nested", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"meta": {"items": [{"text": "x"}], "n": [1, {"a": []}]}, "data": {"total": 2, "items": [{"text": "a"}, {"text": "b"}]}}`)
+	})
+	mux.HandleFunc("/failure", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		fmt.Fprint(w, `{"message": "Invalid argument", "code": 215}`)
+	})
+
+	base := New().Client(client).Base("http://example.com/")
+	cases := []struct {
+		path     string
+		array    string
+		expected []FakeModel
+		err      bool
+	}{
+		{"top", "", []FakeModel{{Text: "a"}, {Text: "b", FavoriteCount: 2}}, false},
+		{"nested", "data.items", []FakeModel{{Text: "a"}, {Text: "b"}}, false},
+		{"nested", "data.missing", nil, false},
+		{"nested", "data.total", nil, true},
+		{"nested", "data.total.items", nil, true},
+		{"top", "data", nil, true},
+	}
+	for _, c := range cases {
+		var models []FakeModel
+		var err error
+		for model, itemErr := range ReceiveArray[FakeModel](base.New().Get(c.path), c.array, nil) {
+			if itemErr != nil {
+				err = itemErr
+				continue
+			}
+			models = append(models, model)
+		}
+		if (err != nil) != c.err {
+			t.Errorf("%s %q: expected error %v, got %v", c.path, c.array, c.err, err)
+		}
+		if !reflect.DeepEqual(c.expected, models) {
+			t.Errorf("%s %q: expected %v, got %v", c.path, c.array, c.expected, models)
+		}
+	}
+
+	apiError := new(APIError)
+	_, err := ReceiveArrayFunc(base.New().Get("failure"), "", apiError, func(model FakeModel) error {
+		t.Errorf("unexpected element %v", model)
+		return nil
+	})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 400 {
+		t.Errorf("expected a 400 *HTTPError, got %v", err)
+	}
+	if apiError.Code != 215 {
+		t.Errorf("expected failureV to be decoded, got %v", apiError)
+	}
+}
+
+func TestReceiveArrayFunc(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "["+strings.Repeat(`{"text": "a"},`, 9999)+`{"text": "a"}]`)
+	})
+
+	count := 0
+	resp, err := ReceiveArrayFunc(New().Client(client).Get("http://example.com/large"), "", nil, func(model FakeModel) error {
+		count++
+		return nil
+	})
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if count != 10000 {
+		t.Errorf("expected %d elements, got %d", 10000, count)
+	}
+}
@@ -0,0 +1,203 @@
+package sling
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTokenRefreshEarly is how long before expiry Slings refresh tokens.
+const defaultTokenRefreshEarly = 30 * time.Second
+
+// Token is an access token sent in the Authorization header.
+type Token struct {
+	// AccessToken is the token value.
+	AccessToken string
+	// TokenType is the Authorization scheme, "Bearer" if empty.
+	TokenType string
+	// Expiry is when the token expires, or zero if it doesn't expire.
+	Expiry time.Time
+}
+
+// Type returns the Authorization scheme of the token.
+func (t Token) Type() string {
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, "bearer") {
+		return "Bearer"
+	}
+	return t.TokenType
+}
+
+// valid returns whether the token is set and doesn't expire within early.
+func (t *Token) valid(early time.Duration) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(early).Before(t.Expiry))
+}
+
+// String returns a description of the token with the AccessToken redacted,
+// so tokens aren't leaked into logs.
+func (t Token) String() string {
+	return fmt.Sprintf("%s token (expiry %v)", t.Type(), t.Expiry)
+}
+
+// GoString is like String, so tokens formatted with %#v are redacted.
+func (t Token) GoString() string {
+	return t.String()
+}
+
+// TokenSource returns access tokens.
+type TokenSource interface {
+	// Token returns a token or an error.
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc is an adapter to allow the use of ordinary functions as
+// TokenSources.
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token calls f(ctx).
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// reuseTokenSource caches tokens from a TokenSource.
+type reuseTokenSource struct {
+	src   TokenSource
+	early time.Duration
+
+	mu    sync.Mutex
+	token *Token
+	// in progress refresh, if any
+	flight *tokenFlight
+}
+
+// tokenFlight is a token refresh shared by concurrent callers.
+type tokenFlight struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// ReuseTokenSource returns a TokenSource which caches the token from src until
+// early before it expires (tokens without an Expiry are reused until
+// rejected). Concurrent callers share a single refresh. If src is already a
+// TokenSource returned by ReuseTokenSource, it is returned as is.
+func ReuseTokenSource(src TokenSource, early time.Duration) TokenSource {
+	if reuse, ok := src.(*reuseTokenSource); ok {
+		return reuse
+	}
+	return &reuseTokenSource{src: src, early: early}
+}
+
+// Token returns the cached token, or refreshes it if it is missing or about
+// to expire. The refresh is shared by concurrent callers, so it isn't
+// cancelled with the context of the caller which started it, but each caller
+// stops waiting when its own context is done.
+func (s *reuseTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	if s.token.valid(s.early) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	flight := s.flight
+	if flight == nil {
+		flight = &tokenFlight{done: make(chan struct{})}
+		s.flight = flight
+		go s.refresh(context.WithoutCancel(ctx), flight)
+	}
+	s.mu.Unlock()
+	select {
+	case <-flight.done:
+		return flight.token, flight.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh gets a token from the source for the flight and caches it.
+func (s *reuseTokenSource) refresh(ctx context.Context, flight *tokenFlight) {
+	flight.token, flight.err = s.src.Token(ctx)
+	if flight.err == nil && flight.token == nil {
+		flight.err = fmt.Errorf("sling: TokenSource returned a nil token")
+	}
+	s.mu.Lock()
+	if flight.err == nil {
+		s.token = flight.token
+	}
+	s.flight = nil
+	close(flight.done)
+	s.mu.Unlock()
+}
+
+// invalidate discards the cached token if it is the rejected token, so the
+// next call to Token refreshes it. Tokens rejected by concurrent requests are
+// only discarded once.
+func (s *reuseTokenSource) invalidate(rejected *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == rejected {
+		s.token = nil
+	}
+}
+
+// TokenAuth returns Middleware which authorizes requests with tokens from the
+// TokenSource, caching and refreshing them like Sling.TokenSource does. Use it
+// to add token authentication to a Doer (see Chain).
+func TokenAuth(src TokenSource) Middleware {
+	reuse := ReuseTokenSource(src, defaultTokenRefreshEarly).(*reuseTokenSource)
+	return func(next Doer) Doer {
+		return &tokenDoer{next: next, src: reuse}
+	}
+}
+
+// tokenDoer sets the Authorization header of requests with tokens from a
+// TokenSource.
+type tokenDoer struct {
+	next Doer
+	src  *reuseTokenSource
+}
+
+// Do sends the request with a token. If the response is a 401 Unauthorized,
+// the token is refreshed and the request is sent once more, if its Body can
+// be re-obtained.
+func (d *tokenDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	token, err := d.src.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("sling: token source: %w", err)
+	}
+	resp, err := d.next.Do(authorize(req, token))
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !rewindable {
+		return resp, err
+	}
+
+	d.src.invalidate(token)
+	refreshed, err := d.src.Token(ctx)
+	if err != nil {
+		// return the 401 response
+		return resp, nil
+	}
+	retry := authorize(req, refreshed)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	io.CopyN(io.Discard, resp.Body, maxDrainRetryBytes)
+	resp.Body.Close()
+	return d.next.Do(retry)
+}
+
+// authorize returns a clone of the request with the token set in the
+// Authorization header.
+func authorize(req *http.Request, token *Token) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", token.Type()+" "+token.AccessToken)
+	return req
+}
@@ -0,0 +1,270 @@
+package sling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBearerAuth(t *testing.T) {
+	req, _ := New().BearerAuth("abc").Request()
+	if auth := req.Header.Get("Authorization"); auth != "Bearer abc" {
+		t.Errorf("expected %s, got %s", "Bearer abc", auth)
+	}
+}
+
+// tokenServer serves tokens from a token endpoint, which expire after the
+// lifetime, and an API endpoint which requires a current token.
+type tokenServer struct {
+	lifetime time.Duration
+	issued   atomic.Int32
+	// rejected tokens
+	mu      sync.Mutex
+	revoked map[string]bool
+}
+
+func (ts *tokenServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	n := ts.issued.Add(1)
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": %d}`, n, int(ts.lifetime/time.Second))
+}
+
+func (ts *tokenServer) handleAPI(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	ts.mu.Lock()
+	revoked := ts.revoked[token]
+	ts.mu.Unlock()
+	if !ok || revoked {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"text": %q}`, token+string(body))
+}
+
+func (ts *tokenServer) revoke(token string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.revoked == nil {
+		ts.revoked = make(map[string]bool)
+	}
+	ts.revoked[token] = true
+}
+
+// tokenSource returns a TokenSource which requests tokens from the token
+// endpoint with the Sling.
+func (ts *tokenServer) tokenSource(s *Sling) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		body := struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}{}
+		_, err := s.New().Post("http://example.com/token").ErrorOnFailure().ReceiveContext(ctx, &body, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &Token{
+			AccessToken: body.AccessToken,
+			Expiry:      time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+		}, nil
+	})
+}
+
+func TestTokenSource(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	ts := &tokenServer{lifetime: time.Hour}
+	mux.HandleFunc("/token", ts.handleToken)
+	mux.HandleFunc("/api", ts.handleAPI)
+
+	tokenClient := New().Client(client)
+	base := New().Client(client).Base("http://example.com/").TokenSource(ts.tokenSource(tokenClient))
+
+	// tokens are cached and shared with children
+	for i := 0; i < 3; i++ {
+		model := new(FakeModel)
+		if _, err := base.New().Get("api").ReceiveSuccess(model); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if model.Text != "token-1" {
+			t.Errorf("expected %s, got %s", "token-1", model.Text)
+		}
+	}
+	if n := ts.issued.Load(); n != 1 {
+		t.Errorf("expected %d token, got %d", 1, n)
+	}
+
+	// rejected tokens are refreshed and the request is retried with its body
+	ts.revoke("token-1")
+	model := new(FakeModel)
+	resp, err := base.New().Post("api").BodyJSON(map[string]int{"n": 1}).ReceiveSuccess(model)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if expected := "token-2{\"n\":1}\n"; model.Text != expected {
+		t.Errorf("expected %q, got %q", expected, model.Text)
+	}
+
+	// tokens are refreshed once for concurrent 401 responses
+	ts.revoke("token-2")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			model := new(FakeModel)
+			if _, err := base.New().Get("api").ReceiveSuccess(model); err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+			if model.Text != "token-3" {
+				t.Errorf("expected %s, got %s", "token-3", model.Text)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := ts.issued.Load(); n != 3 {
+		t.Errorf("expected %d tokens, got %d", 3, n)
+	}
+
+	// requests are only retried once
+	ts.revoke("token-3")
+	ts.revoke("token-4")
+	resp, err = base.New().Get("api").ReceiveSuccess(nil)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a 401 response, got %v %v", resp, err)
+	}
+}
+
+func TestTokenSource_refreshBeforeExpiry(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	// tokens expire within the refresh window
+	ts := &tokenServer{lifetime: 10 * time.Second}
+	mux.HandleFunc("/token", ts.handleToken)
+	mux.HandleFunc("/api", ts.handleAPI)
+
+	base := New().Client(client).Base("http://example.com/").TokenSource(ts.tokenSource(New().Client(client)))
+	for i := 1; i <= 2; i++ {
+		model := new(FakeModel)
+		if _, err := base.New().Get("api").ReceiveSuccess(model); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if expected := "token-" + strconv.Itoa(i); model.Text != expected {
+			t.Errorf("expected %s, got %s", expected, model.Text)
+		}
+	}
+}
+
+func TestTokenSource_errors(t *testing.T) {
+	errTokenEndpoint := errors.New("token endpoint unavailable")
+	s := New().Get("http://example.com/api").TokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return nil, errTokenEndpoint
+	}))
+	if _, err := s.ReceiveSuccess(nil); !errors.Is(err, errTokenEndpoint) {
+		t.Errorf("expected %v, got %v", errTokenEndpoint, err)
+	}
+}
+
+func TestReuseTokenSource_contextCancel(t *testing.T) {
+	refreshing := make(chan struct{})
+	release := make(chan struct{})
+	src := ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		close(refreshing)
+		<-release
+		return &Token{AccessToken: "abc"}, nil
+	}), 0)
+	go src.Token(context.Background())
+	<-refreshing
+
+	// callers waiting for a refresh stop when their context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := src.Token(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	close(release)
+}
+
+func TestReuseTokenSource_leaderCancel(t *testing.T) {
+	refreshing := make(chan struct{})
+	release := make(chan struct{})
+	src := ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		close(refreshing)
+		select {
+		case <-release:
+			return &Token{AccessToken: "abc"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := src.Token(ctx)
+		leaderErr <- err
+	}()
+	<-refreshing
+
+	// cancelling the caller which started a refresh doesn't fail the refresh
+	// shared with other callers
+	waiter := make(chan *Token)
+	go func() {
+		token, err := src.Token(context.Background())
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		waiter <- token
+	}()
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	close(release)
+	if token := <-waiter; token == nil || token.AccessToken != "abc" {
+		t.Errorf("expected refreshed token, got %v", token)
+	}
+}
+
+func TestToken_redacted(t *testing.T) {
+	token := &Token{AccessToken: "secret", TokenType: "bearer"}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if s := fmt.Sprintf(format, token); strings.Contains(s, "secret") {
+			t.Errorf("%s: expected AccessToken to be redacted, got %s", format, s)
+		}
+	}
+	if b, _ := json.Marshal(token); !strings.Contains(string(b), "secret") {
+		t.Errorf("expected tokens to be JSON encoded, got %s", b)
+	}
+	if token.Type() != "Bearer" {
+		t.Errorf("expected %s, got %s", "Bearer", token.Type())
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	ts := &tokenServer{lifetime: time.Hour}
+	mux.HandleFunc("/token", ts.handleToken)
+	mux.HandleFunc("/api", ts.handleAPI)
+
+	doer := Chain(client, TokenAuth(ts.tokenSource(New().Client(client))))
+	for i := 0; i < 2; i++ {
+		model := new(FakeModel)
+		if _, err := New().Doer(doer).Get("http://example.com/api").ReceiveSuccess(model); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if model.Text != "token-1" {
+			t.Errorf("expected %s, got %s", "token-1", model.Text)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package sling
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net/url"
 	"strings"
 
 	goquery "github.com/google/go-querystring/query"
@@ -17,6 +19,52 @@ type BodyProvider interface {
 	Body() (io.Reader, error)
 }
 
+// ContextBodyProvider is a BodyProvider which can stop encoding the body when
+// a context is done. Sling uses BodyContext instead of Body when a provider
+// implements it.
+type ContextBodyProvider interface {
+	BodyProvider
+	// BodyContext returns the io.Reader body, returning ctx.Err() if the
+	// context is done before the body is encoded.
+	BodyContext(ctx context.Context) (io.Reader, error)
+}
+
+// provideBody returns the Body from the BodyProvider, returning the context's
+// error if the context is done before or while the body is encoded.
+func provideBody(ctx context.Context, provider BodyProvider) (io.Reader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	var err error
+	if cp, ok := provider.(ContextBodyProvider); ok {
+		body, err = cp.BodyContext(ctx)
+	} else {
+		body, err = provider.Body()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// replayable returns whether the BodyProvider can provide its Body again for
+// retries and redirects. Providers of one-shot readers (e.g. raw Bodies,
+// FileReader parts, and NDJSONChan) implement replayable() to report they
+// can't.
+func replayable(provider BodyProvider) bool {
+	if p, ok := provider.(interface{ replayable() bool }); ok {
+		return p.replayable()
+	}
+	return true
+}
+
 // bodyProvider provides the wrapped body value as a Body for reqests.
 type bodyProvider struct {
 	body io.Reader
@@ -30,6 +78,10 @@ func (p bodyProvider) Body() (io.Reader, error) {
 	return p.body, nil
 }
 
+func (p bodyProvider) replayable() bool {
+	return false
+}
+
 // jsonBodyProvider encodes a JSON tagged struct value as a Body for requests.
 // See https://golang.org/pkg/encoding/json/#MarshalIndent for details.
 type jsonBodyProvider struct {
@@ -49,7 +101,8 @@ func (p jsonBodyProvider) Body() (io.Reader, error) {
 	return buf, nil
 }
 
-// formBodyProvider encodes a url tagged struct value as Body for requests.
+// formBodyProvider encodes a url tagged struct value or url.Values as Body
+// for requests.
 // See https://godoc.org/github.com/google/go-querystring/query for details.
 type formBodyProvider struct {
 	payload interface{}
@@ -60,6 +113,9 @@ func (p formBodyProvider) ContentType() string {
 }
 
 func (p formBodyProvider) Body() (io.Reader, error) {
+	if values, ok := p.payload.(url.Values); ok {
+		return strings.NewReader(values.Encode()), nil
+	}
 	values, err := goquery.Values(p.payload)
 	if err != nil {
 		return nil, err
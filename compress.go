@@ -0,0 +1,211 @@
+package sling
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Content-Encodings for CompressBody.
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// DefaultMaxDecompressionRatio is the default limit on the ratio of the
+// decompressed size of a response Body to its compressed size.
+const DefaultMaxDecompressionRatio = 100
+
+// minDecompressionLimit is the decompressed size below which the
+// decompression ratio isn't checked, since small Bodies can legitimately
+// have high ratios.
+const minDecompressionLimit = 1 << 20
+
+// compressChunkSize is the size of chunks read from Bodies to compress.
+const compressChunkSize = 32 << 10
+
+// bodyCompression configures request Body compression.
+type bodyCompression struct {
+	encoding string
+	minSize  int
+}
+
+// DecompressionRatioError is returned when reading a compressed response Body
+// which decompresses to more than the maximum ratio of its compressed size,
+// such as a decompression bomb.
+type DecompressionRatioError struct {
+	// Encoding is the response Content-Encoding (e.g. "gzip").
+	Encoding string
+	// MaxRatio is the maximum decompression ratio.
+	MaxRatio int
+}
+
+func (e *DecompressionRatioError) Error() string {
+	return fmt.Sprintf("sling: %s response Body exceeds decompression ratio %d", e.Encoding, e.MaxRatio)
+}
+
+// compressRequest replaces the request Body with a streaming compressor and
+// sets the Content-Encoding header, unless the Body has a known length below
+// minSize. Bodies of unknown length (e.g. streamed multipart or NDJSON
+// Bodies) are compressed without reading them first, so their content isn't
+// held back or buffered to decide.
+func compressRequest(req *http.Request, compression *bodyCompression) error {
+	body := req.Body
+	if compression.encoding != EncodingGzip && compression.encoding != EncodingDeflate {
+		body.Close()
+		return fmt.Errorf("sling: unsupported Content-Encoding %q", compression.encoding)
+	}
+	if req.ContentLength > 0 && req.ContentLength < int64(compression.minSize) {
+		// below the threshold, send the Body uncompressed
+		return nil
+	}
+	req.Body = newCompressReader(body, compression.encoding)
+	req.ContentLength = -1
+	req.Header.Set("Content-Encoding", compression.encoding)
+	req.Header.Del("Content-Length")
+	if getBody := req.GetBody; getBody != nil {
+		// re-obtained Bodies have the same content, so are also compressed
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return newCompressReader(body, compression.encoding), nil
+		}
+	}
+	return nil
+}
+
+// compressReader compresses the content read from a Body as it is read,
+// without buffering the Body or starting a goroutine.
+type compressReader struct {
+	src   io.ReadCloser
+	zw    io.WriteCloser
+	buf   bytes.Buffer
+	chunk []byte
+	// error reading src, or io.EOF once the compressor is closed
+	err error
+}
+
+func newCompressReader(src io.ReadCloser, encoding string) *compressReader {
+	r := &compressReader{src: src, chunk: make([]byte, compressChunkSize)}
+	if encoding == EncodingDeflate {
+		r.zw = zlib.NewWriter(&r.buf)
+	} else {
+		r.zw = gzip.NewWriter(&r.buf)
+	}
+	return r
+}
+
+func (r *compressReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		n, err := r.src.Read(r.chunk)
+		// writes to a bytes.Buffer don't fail
+		r.zw.Write(r.chunk[:n])
+		if err == io.EOF {
+			r.zw.Close()
+		}
+		r.err = err
+	}
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+	return 0, r.err
+}
+
+func (r *compressReader) Close() error {
+	return r.src.Close()
+}
+
+// decompressResponse replaces the Body of a gzip or deflate encoded response
+// which the Transport didn't decompress (e.g. because the Accept-Encoding
+// header was set explicitly) with a decompressing reader and returns whether
+// it did. If maxRatio is positive, reads return a *DecompressionRatioError
+// once the decompressed size exceeds maxRatio times the compressed size.
+func decompressResponse(req *http.Request, resp *http.Response, maxRatio int) bool {
+	if req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		return false
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case EncodingGzip, "x-gzip", EncodingDeflate:
+	default:
+		return false
+	}
+	compressed := &countingReader{r: resp.Body}
+	resp.Body = readCloser{
+		Reader: &decompressReader{src: compressed, encoding: encoding, maxRatio: maxRatio},
+		Closer: resp.Body,
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return true
+}
+
+// countingReader counts the bytes read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// decompressReader decompresses a Body, creating the decompressor on the
+// first Read, and limits the decompression ratio.
+type decompressReader struct {
+	src      *countingReader
+	encoding string
+	maxRatio int
+	zr       io.Reader
+	n        int64
+	// ratio error, returned by every Read once the limit is exceeded
+	err error
+}
+
+func (r *decompressReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.zr == nil {
+		zr, err := newDecompressor(r.src, r.encoding)
+		if err != nil {
+			return 0, err
+		}
+		r.zr = zr
+	}
+	n, err := r.zr.Read(p)
+	r.n += int64(n)
+	if r.maxRatio > 0 && r.n > minDecompressionLimit && r.n > int64(r.maxRatio)*r.src.n {
+		// drop the content read, so readers which ignore errors returned
+		// with content can't decode past the limit
+		r.err = &DecompressionRatioError{Encoding: r.encoding, MaxRatio: r.maxRatio}
+		return 0, r.err
+	}
+	return n, err
+}
+
+// newDecompressor returns a reader which decompresses gzip or deflate
+// content. Deflate content is usually zlib wrapped (RFC 1950), but some
+// servers send raw deflate (RFC 1951) data.
+func newDecompressor(r io.Reader, encoding string) (io.Reader, error) {
+	if encoding != EncodingDeflate {
+		return gzip.NewReader(r)
+	}
+	br := bufio.NewReader(r)
+	if header, err := br.Peek(2); err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,265 @@
+package sling
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// compress returns the content compressed with the Content-Encoding, or raw
+// deflate data for "raw".
+func compress(t *testing.T, encoding, content string) []byte {
+	buf := new(bytes.Buffer)
+	var zw io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		zw = gzip.NewWriter(buf)
+	case EncodingDeflate:
+		zw = zlib.NewWriter(buf)
+	case "raw":
+		zw, _ = flate.NewWriter(buf, flate.DefaultCompression)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	io.WriteString(zw, content)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// decompress returns the decompressed content of a request Body.
+func decompress(t *testing.T, encoding string, body io.Reader) string {
+	var zr io.Reader
+	var err error
+	switch encoding {
+	case EncodingGzip:
+		zr, err = gzip.NewReader(body)
+	case EncodingDeflate:
+		zr, err = zlib.NewReader(body)
+	default:
+		zr = body
+	}
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	return string(content)
+}
+
+func TestCompressBody(t *testing.T) {
+	text := strings.Repeat("compressible ", 1000)
+	jsonText := "{\"text\":\"" + text + "\"}\n"
+	cases := []struct {
+		encoding string
+		minSize  int
+		body     *Sling
+		content  string
+		expected string
+	}{
+		{EncodingGzip, 1024, New().BodyJSON(&FakeModel{Text: text}), jsonText, EncodingGzip},
+		{EncodingDeflate, 0, New().BodyJSON(&FakeModel{Text: text}), jsonText, EncodingDeflate},
+		{EncodingGzip, 1024, New().Body(strings.NewReader(text)), text, EncodingGzip},
+		// Bodies of unknown length are compressed without reading them first
+		{EncodingGzip, 1024, New().Body(io.MultiReader(strings.NewReader(text))), text, EncodingGzip},
+		{EncodingGzip, 1 << 20, New().Body(io.MultiReader(strings.NewReader("short"))), "short", EncodingGzip},
+		// Bodies below the threshold aren't compressed
+		{EncodingGzip, 1 << 20, New().BodyJSON(&FakeModel{Text: text}), jsonText, ""},
+		{EncodingGzip, 1024, New().Body(strings.NewReader("short")), "short", ""},
+		{"", 0, New().BodyJSON(&FakeModel{Text: text}), jsonText, ""},
+	}
+	for _, c := range cases {
+		client, mux, server := testServer()
+		var received string
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			encoding := r.Header.Get("Content-Encoding")
+			if encoding != c.expected {
+				t.Errorf("expected Content-Encoding %q, got %q", c.expected, encoding)
+			}
+			// compressed Bodies are streamed with an unknown length
+			if encoding != "" && r.ContentLength != -1 {
+				t.Errorf("expected unknown Content-Length, got %d", r.ContentLength)
+			}
+			received = decompress(t, encoding, r.Body)
+		})
+
+		_, err := c.body.Client(client).Post("http://example.com/").CompressBody(c.encoding, c.minSize).ReceiveSuccess(nil)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		if received != c.content {
+			t.Errorf("expected %q, got %q", c.content, received)
+		}
+		server.Close()
+	}
+}
+
+func TestCompressBody_getBody(t *testing.T) {
+	text := strings.Repeat("compressible ", 1000)
+	req, err := New().Post("http://example.com/").BodyJSON(&FakeModel{Text: text}).CompressBody(EncodingGzip, 0).ContentDigest(DigestSHA256).Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	sent, _ := io.ReadAll(req.Body)
+	// retries and redirects re-obtain the same compressed Body
+	body, err := req.GetBody()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if again, _ := io.ReadAll(body); !bytes.Equal(sent, again) {
+		t.Errorf("expected re-obtained Body to match")
+	}
+	if content := decompress(t, EncodingGzip, bytes.NewReader(sent)); !strings.Contains(content, text) {
+		t.Errorf("expected compressed Body, got %q", content)
+	}
+	// the Content-Digest is of the compressed content
+	sum := sha256.Sum256(sent)
+	if expected := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"; req.Header.Get("Content-Digest") != expected {
+		t.Errorf("expected %s, got %s", expected, req.Header.Get("Content-Digest"))
+	}
+
+	if _, err := New().Post("http://example.com/").BodyJSON(modelA).CompressBody("br", 0).Request(); err == nil {
+		t.Errorf("expected unsupported encoding error, got nil")
+	}
+}
+
+func TestCompressBody_stream(t *testing.T) {
+	ch := make(chan FakeModel)
+	// creating the request doesn't wait for the threshold to be streamed
+	req, err := New().Post("http://example.com/").BodyProvider(NDJSONChan(ch)).CompressBody(EncodingGzip, 64<<20).Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	go func() {
+		defer close(ch)
+		ch <- modelA
+	}()
+	if req.Header.Get("Content-Encoding") != EncodingGzip {
+		t.Errorf("expected Content-Encoding %s, got %s", EncodingGzip, req.Header.Get("Content-Encoding"))
+	}
+	if content, expected := decompress(t, EncodingGzip, req.Body), "{\"text\":\"note\",\"favorite_count\":12}\n"; content != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+}
+
+func TestDecompressResponse(t *testing.T) {
+	const body = `{"text": "Some text", "favorite_count": 24}`
+	cases := []struct {
+		encoding       string
+		acceptEncoding string
+	}{
+		// the Transport doesn't decompress deflate
+		{EncodingDeflate, ""},
+		{"raw", ""},
+		// or gzip when Accept-Encoding is set explicitly
+		{EncodingGzip, "gzip"},
+		{EncodingGzip, ""},
+	}
+	for _, c := range cases {
+		client, mux, server := testServer()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			encoding := c.encoding
+			if encoding == "raw" {
+				encoding = EncodingDeflate
+			}
+			w.Header().Set("Content-Encoding", encoding)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(compress(t, c.encoding, body))
+		})
+		s := New().Client(client).Get("http://example.com/")
+		if c.acceptEncoding != "" {
+			s = s.Set("Accept-Encoding", c.acceptEncoding)
+		}
+		model := new(FakeModel)
+		resp, err := s.ReceiveSuccess(model)
+		if err != nil {
+			t.Errorf("%v: expected nil, got %v", c, err)
+		}
+		if model.Text != "Some text" || model.FavoriteCount != 24 {
+			t.Errorf("%v: expected decoded model, got %v", c, model)
+		}
+		if resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed {
+			t.Errorf("%v: expected decompressed response, got %v", c, resp.Header)
+		}
+		server.Close()
+	}
+}
+
+func TestDecompressResponse_ratio(t *testing.T) {
+	body := compress(t, EncodingGzip, `{"text": "`+strings.Repeat("a", 4<<20)+`"}`)
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", EncodingGzip)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+	base := New().Client(client).Get("http://example.com/").Set("Accept-Encoding", "gzip")
+
+	model := new(FakeModel)
+	_, err := base.New().ReceiveSuccess(model)
+	var ratioErr *DecompressionRatioError
+	if !errors.As(err, &ratioErr) || ratioErr.MaxRatio != DefaultMaxDecompressionRatio {
+		t.Errorf("expected DecompressionRatioError, got %v", err)
+	}
+	if _, err := base.New().MaxDecompressionRatio(-1).ReceiveSuccess(model); err != nil || len(model.Text) != 4<<20 {
+		t.Errorf("expected decoded model, got %v", err)
+	}
+}
+
+func TestDecompressResponse_stream(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", EncodingGzip)
+		w.Write(compress(t, EncodingGzip, "content"))
+	})
+	mux.HandleFunc("/records", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", EncodingDeflate)
+		w.Write(compress(t, EncodingDeflate, "{\"text\":\"a\"}\n{\"text\":\"b\"}\n"))
+	})
+	mux.HandleFunc("/failure", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", EncodingDeflate)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(compress(t, EncodingDeflate, `{"message": "invalid", "code": 400}`))
+	})
+	base := New().Client(client).Base("http://example.com/")
+
+	// streamed responses are decompressed like decoded responses
+	resp, err := base.New().Get("file").Set("Accept-Encoding", "gzip").ReceiveStream(nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	defer resp.Body.Close()
+	if content, _ := io.ReadAll(resp.Body); string(content) != "content" || !resp.Uncompressed {
+		t.Errorf("expected decompressed content, got %q", content)
+	}
+
+	var texts []string
+	for model, err := range ReceiveNDJSON[FakeModel](base.New().Get("records"), nil) {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		texts = append(texts, model.Text)
+	}
+	if len(texts) != 2 || texts[0] != "a" || texts[1] != "b" {
+		t.Errorf("expected decoded records, got %v", texts)
+	}
+
+	apiError := new(APIError)
+	_, err = base.New().Get("failure").ReceiveStream(apiError)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || apiError.Code != 400 {
+		t.Errorf("expected decoded failure, got %v %v", err, apiError)
+	}
+}
@@ -0,0 +1,303 @@
+package sling
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestAlgorithms are the supported Digest algorithms, strongest first.
+var digestAlgorithms = []string{"SHA-256", "SHA-256-sess", "MD5", "MD5-sess"}
+
+// digestChallenge is a WWW-Authenticate Digest challenge (RFC 7616 Section
+// 3.3).
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	userhash  bool
+}
+
+// digestAuth holds Digest credentials and the last challenge, which is shared
+// by a Sling and its children to authorize requests without a round trip.
+type digestAuth struct {
+	username string
+	password string
+	// cnonce returns client nonces
+	cnonce func() string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	// nonce count of the challenge nonce
+	nc uint32
+}
+
+func newDigestAuth(username, password string) *digestAuth {
+	return &digestAuth{username: username, password: password, cnonce: randomID}
+}
+
+// setChallenge caches the challenge, resetting the nonce count if the nonce
+// changed.
+func (a *digestAuth) setChallenge(challenge *digestChallenge) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.challenge == nil || a.challenge.nonce != challenge.nonce {
+		a.nc = 0
+	}
+	a.challenge = challenge
+}
+
+// authorize returns a clone of the request with a Digest Authorization
+// header for the cached challenge, or the request if there is no challenge.
+func (a *digestAuth) authorize(req *http.Request) (*http.Request, error) {
+	a.mu.Lock()
+	challenge := a.challenge
+	if challenge == nil {
+		a.mu.Unlock()
+		return req, nil
+	}
+	a.nc++
+	nc := a.nc
+	a.mu.Unlock()
+
+	// algorithm names are case-insensitive
+	algorithm := strings.ToUpper(challenge.algorithm)
+	newHash := md5.New
+	if strings.HasPrefix(algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		hash := newHash()
+		io.WriteString(hash, s)
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+
+	req = req.Clone(req.Context())
+	uri := req.URL.RequestURI()
+	cnonce := a.cnonce()
+	ncValue := fmt.Sprintf("%08x", nc)
+	a1 := h(a.username + ":" + challenge.realm + ":" + a.password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		a1 = h(a1 + ":" + challenge.nonce + ":" + cnonce)
+	}
+	a2 := req.Method + ":" + uri
+	if challenge.qop == "auth-int" {
+		bodyHash := newHash()
+		if err := HashBody(req, bodyHash); err != nil {
+			return nil, fmt.Errorf("sling: digest auth: %w", err)
+		}
+		a2 += ":" + hex.EncodeToString(bodyHash.Sum(nil))
+	}
+	var response string
+	if challenge.qop == "" {
+		// RFC 2069 compatibility
+		response = h(a1 + ":" + challenge.nonce + ":" + h(a2))
+	} else {
+		response = h(a1 + ":" + challenge.nonce + ":" + ncValue + ":" + cnonce + ":" + challenge.qop + ":" + h(a2))
+	}
+
+	username := a.username
+	if challenge.userhash {
+		username = h(a.username + ":" + challenge.realm)
+	}
+	params := []string{
+		"username=" + quoteParam(username),
+		"realm=" + quoteParam(challenge.realm),
+		"uri=" + quoteParam(uri),
+		"algorithm=" + challenge.algorithm,
+		"nonce=" + quoteParam(challenge.nonce),
+	}
+	if challenge.qop != "" {
+		params = append(params, "nc="+ncValue, "cnonce="+quoteParam(cnonce), "qop="+challenge.qop)
+	}
+	params = append(params, "response="+quoteParam(response))
+	if challenge.opaque != "" {
+		params = append(params, "opaque="+quoteParam(challenge.opaque))
+	}
+	if challenge.userhash {
+		params = append(params, "userhash=true")
+	}
+	req.Header.Set("Authorization", "Digest "+strings.Join(params, ", "))
+	return req, nil
+}
+
+// digestDoer authorizes requests with HTTP Digest Authentication.
+type digestDoer struct {
+	next Doer
+	auth *digestAuth
+}
+
+// Do sends the request, authorized with the cached challenge if there is
+// one. If the response is a 401 Unauthorized with a Digest challenge, the
+// challenge is cached and the request is sent once more, if its Body can be
+// re-obtained.
+func (d *digestDoer) Do(req *http.Request) (*http.Response, error) {
+	authorized, err := d.auth.authorize(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.next.Do(authorized)
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !rewindable {
+		return resp, err
+	}
+	challenge := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil {
+		return resp, nil
+	}
+	d.auth.setChallenge(challenge)
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	retry, err = d.auth.authorize(retry)
+	if err != nil {
+		return resp, nil
+	}
+	io.CopyN(io.Discard, resp.Body, maxDrainRetryBytes)
+	resp.Body.Close()
+	return d.next.Do(retry)
+}
+
+// parseDigestChallenge returns the Digest challenge with the strongest
+// supported algorithm and qop, or nil if there is none.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	rank := func(c *digestChallenge) int {
+		for i, algorithm := range digestAlgorithms {
+			if strings.EqualFold(c.algorithm, algorithm) {
+				return i
+			}
+		}
+		return len(digestAlgorithms)
+	}
+	for _, value := range values {
+		for _, challenge := range parseChallenges(value) {
+			if !strings.EqualFold(challenge.scheme, "Digest") {
+				continue
+			}
+			c := &digestChallenge{
+				realm:     challenge.params["realm"],
+				nonce:     challenge.params["nonce"],
+				opaque:    challenge.params["opaque"],
+				algorithm: challenge.params["algorithm"],
+				userhash:  strings.EqualFold(challenge.params["userhash"], "true"),
+			}
+			if c.algorithm == "" {
+				c.algorithm = "MD5"
+			}
+			if qop, ok := challenge.params["qop"]; ok {
+				// prefer auth, which doesn't need the body
+				for _, option := range strings.Split(qop, ",") {
+					option = strings.TrimSpace(option)
+					if option == "auth" || option == "auth-int" && c.qop == "" {
+						c.qop = option
+					}
+				}
+				if c.qop == "" {
+					continue
+				}
+			}
+			if c.nonce == "" || rank(c) == len(digestAlgorithms) {
+				continue
+			}
+			if best == nil || rank(c) < rank(best) {
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+// authChallenge is a WWW-Authenticate challenge with its auth-params, keyed
+// by lowercase name.
+type authChallenge struct {
+	scheme string
+	params map[string]string
+}
+
+// parseChallenges parses the challenges of a WWW-Authenticate header value
+// (RFC 9110 Section 11.6.1). Token68 credentials are ignored.
+func parseChallenges(value string) []authChallenge {
+	var challenges []authChallenge
+	s := value
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return challenges
+		}
+		token, rest := cutToken(s)
+		if token == "" {
+			// skip unexpected characters
+			s = s[1:]
+			continue
+		}
+		rest = strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(rest, "=") && len(challenges) > 0 {
+			// auth-param of the current challenge
+			var param string
+			param, s = cutParamValue(strings.TrimLeft(rest[1:], " \t"))
+			challenges[len(challenges)-1].params[strings.ToLower(token)] = param
+			continue
+		}
+		challenges = append(challenges, authChallenge{scheme: token, params: map[string]string{}})
+		s = rest
+	}
+}
+
+// cutToken returns the leading token of s and the remainder.
+func cutToken(s string) (string, string) {
+	i := 0
+	for i < len(s) && (isAlphaNum(s[i]) || strings.IndexByte("!#$%&'*+-.^_`|~", s[i]) >= 0) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// cutParamValue returns the leading token or quoted-string of s, unquoted,
+// and the remainder.
+func cutParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		i := strings.IndexAny(s, ", \t")
+		if i < 0 {
+			return s, ""
+		}
+		return s[:i], s[i:]
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// quoteParam returns the quoted-string of s.
+func quoteParam(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func isAlphaNum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
@@ -0,0 +1,236 @@
+package sling
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDigestAuth_vectors(t *testing.T) {
+	cases := []struct {
+		name      string
+		username  string
+		password  string
+		challenge *digestChallenge
+		cnonce    string
+		expected  string
+	}{
+		// RFC 2617 Section 3.5
+		{"RFC 2617", "Mufasa", "Circle Of Life", &digestChallenge{
+			realm:     "testrealm@host.com",
+			nonce:     "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+			opaque:    "5ccc069c403ebaf9f0171e9517f40e41",
+			algorithm: "MD5",
+			qop:       "auth",
+		}, "0a4f113b", "6629fae49393a05397450978507c4ef1"},
+		// RFC 7616 Section 3.9.1
+		{"RFC 7616 MD5", "Mufasa", "Circle of Life", &digestChallenge{
+			realm:     "http-auth@example.org",
+			nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+			opaque:    "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+			algorithm: "MD5",
+			qop:       "auth",
+		}, "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"RFC 7616 SHA-256", "Mufasa", "Circle of Life", &digestChallenge{
+			realm:     "http-auth@example.org",
+			nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+			opaque:    "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+			algorithm: "SHA-256",
+			qop:       "auth",
+		}, "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+	for _, c := range cases {
+		auth := newDigestAuth(c.username, c.password)
+		auth.cnonce = func() string { return c.cnonce }
+		auth.setChallenge(c.challenge)
+		req, _ := http.NewRequest("GET", "http://www.example.org/dir/index.html", nil)
+		req, err := auth.authorize(req)
+		if err != nil {
+			t.Fatalf("%s: expected nil, got %v", c.name, err)
+		}
+		params := parseChallenges(req.Header.Get("Authorization"))[0].params
+		if params["response"] != c.expected {
+			t.Errorf("%s: expected response %s, got %s", c.name, c.expected, params["response"])
+		}
+		if params["nc"] != "00000001" || params["uri"] != "/dir/index.html" || params["opaque"] != c.challenge.opaque {
+			t.Errorf("%s: unexpected Authorization %s", c.name, req.Header.Get("Authorization"))
+		}
+	}
+}
+
+// digestServer is a handler which requires Digest authentication.
+type digestServer struct {
+	t         *testing.T
+	username  string
+	password  string
+	algorithm string
+	qop       string
+
+	mu sync.Mutex
+	// nonce issued in challenges
+	nonce int
+	// last nonce count received
+	nc int64
+	// requests received, including unauthorized requests
+	requests int
+}
+
+func (s *digestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	body, _ := io.ReadAll(r.Body)
+	challenges := parseChallenges(r.Header.Get("Authorization"))
+	if len(challenges) == 0 || challenges[0].scheme != "Digest" {
+		s.challenge(w, false)
+		return
+	}
+	params := challenges[0].params
+	if params["nonce"] != strconv.Itoa(s.nonce) {
+		s.challenge(w, true)
+		return
+	}
+	nc, _ := strconv.ParseInt(params["nc"], 16, 64)
+	if nc <= s.nc {
+		s.t.Errorf("expected nonce count > %d, got %d", s.nc, nc)
+	}
+	s.nc = nc
+
+	newHash := md5.New
+	if strings.HasPrefix(strings.ToUpper(s.algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(parts ...string) string {
+		hash := newHash()
+		io.WriteString(hash, strings.Join(parts, ":"))
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+	ha1 := h(s.username, params["realm"], s.password)
+	if strings.HasSuffix(strings.ToUpper(s.algorithm), "-SESS") {
+		ha1 = h(ha1, params["nonce"], params["cnonce"])
+	}
+	ha2 := h(r.Method, params["uri"])
+	if s.qop == "auth-int" {
+		ha2 = h(r.Method, params["uri"], hashHex(newHash(), body))
+	}
+	expected := h(ha1, params["nonce"], params["nc"], params["cnonce"], params["qop"], ha2)
+	if params["uri"] != r.URL.RequestURI() || params["algorithm"] != s.algorithm || params["qop"] != s.qop {
+		s.t.Errorf("unexpected Authorization %s", r.Header.Get("Authorization"))
+	}
+	if params["response"] != expected {
+		s.challenge(w, false)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"text": %q}`, body)
+}
+
+// challenge responds with a 401 and a new nonce.
+func (s *digestServer) challenge(w http.ResponseWriter, stale bool) {
+	s.nonce++
+	s.nc = 0
+	w.Header().Add("WWW-Authenticate", `Basic realm="lab"`)
+	w.Header().Add("WWW-Authenticate", fmt.Sprintf(`Digest realm="lab", qop="%s", algorithm=%s, nonce="%d", opaque="xyz", stale=%t`, s.qop, s.algorithm, s.nonce, stale))
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func hashHex(h hash.Hash, b []byte) string {
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestDigestAuth(t *testing.T) {
+	cases := []struct {
+		algorithm string
+		qop       string
+	}{
+		{"MD5", "auth"},
+		{"MD5-sess", "auth"},
+		{"SHA-256", "auth"},
+		{"SHA-256-sess", "auth-int"},
+		{"MD5", "auth-int"},
+		// algorithm names are case-insensitive
+		{"md5", "auth"},
+		{"sha-256-SESS", "auth"},
+	}
+	for _, c := range cases {
+		client, mux, server := testServer()
+		ds := &digestServer{t: t, username: "user", password: "pass", algorithm: c.algorithm, qop: c.qop}
+		mux.Handle("/", ds)
+
+		base := New().Client(client).Base("http://example.com/").DigestAuth("user", "pass")
+		for i := 1; i <= 3; i++ {
+			model := new(FakeModel)
+			resp, err := base.New().Post("instruments").BodyJSON(map[string]int{"n": i}).ReceiveSuccess(model)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Fatalf("%v: expected 200 OK, got %v %v", c, resp, err)
+			}
+			if expected := fmt.Sprintf("{\"n\":%d}\n", i); model.Text != expected {
+				t.Errorf("%v: expected %q, got %q", c, expected, model.Text)
+			}
+		}
+		// the challenge is cached after the first round trip
+		if ds.requests != 4 || ds.nc != 3 {
+			t.Errorf("%v: expected 4 requests and nonce count 3, got %d and %d", c, ds.requests, ds.nc)
+		}
+
+		// stale nonces are replaced
+		ds.nonce++
+		if resp, err := base.New().Get("instruments").ReceiveSuccess(nil); err != nil || resp.StatusCode != http.StatusOK {
+			t.Errorf("%v: expected 200 OK, got %v %v", c, resp, err)
+		}
+		if ds.requests != 6 || ds.nc != 1 {
+			t.Errorf("%v: expected 6 requests and nonce count 1, got %d and %d", c, ds.requests, ds.nc)
+		}
+		server.Close()
+	}
+}
+
+func TestDigestAuth_wrongPassword(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	ds := &digestServer{t: t, username: "user", password: "pass", algorithm: "MD5", qop: "auth"}
+	mux.Handle("/", ds)
+
+	resp, err := New().Client(client).Get("http://example.com/").DigestAuth("user", "wrong").ReceiveSuccess(nil)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 Unauthorized, got %v %v", resp, err)
+	}
+	if ds.requests != 2 {
+		t.Errorf("expected %d requests, got %d", 2, ds.requests)
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	cases := []struct {
+		values   []string
+		expected *digestChallenge
+	}{
+		{[]string{`Basic realm="a"`}, nil},
+		// multiple challenges in one header value, quoted commas and escapes
+		{[]string{`Basic realm="a", Digest realm="b, \"c\"", qop="auth-int, auth", nonce=n1, algorithm=MD5-sess`},
+			&digestChallenge{realm: `b, "c"`, nonce: "n1", algorithm: "MD5-sess", qop: "auth"}},
+		// the strongest algorithm is preferred
+		{[]string{`Digest realm="a", nonce="n1"`, `Digest realm="a", nonce="n2", algorithm=SHA-256, qop="auth-int", userhash=true`},
+			&digestChallenge{realm: "a", nonce: "n2", algorithm: "SHA-256", qop: "auth-int", userhash: true}},
+		// algorithm names are case-insensitive
+		{[]string{`Digest realm="a", nonce="n1", algorithm=md5`, `Digest realm="a", nonce="n2", algorithm=sha-256, qop=auth`},
+			&digestChallenge{realm: "a", nonce: "n2", algorithm: "sha-256", qop: "auth"}},
+		// unsupported algorithms and qops
+		{[]string{`Digest realm="a", nonce="n1", algorithm=SHA-512-256`, `Digest realm="a", nonce="n2", qop="other"`}, nil},
+	}
+	for _, c := range cases {
+		challenge := parseDigestChallenge(c.values)
+		if (challenge == nil) != (c.expected == nil) || challenge != nil && *challenge != *c.expected {
+			t.Errorf("expected %+v, got %+v", c.expected, challenge)
+		}
+	}
+}
@@ -31,6 +31,17 @@ except they set the HTTP method too.
 
 	req, err := sling.New().Post("http://upload.com/gophers")
 
+Paths may be RFC 6570 URI templates, whose variables are set with PathParams
+from a map or a struct with path tagged fields. Values are percent-encoded and
+missing variables return an error when the Request is created.
+
+	// creates a GET request to https://api.github.com/repos/golang/go/issues?state=open
+	req, err := githubBase.New().Get("repos/{owner}/{repo}/issues{?state}").PathParams(map[string]string{
+	    "owner": "golang",
+	    "repo":  "go",
+	    "state": "open",
+	}).Request()
+
 # Headers
 
 Add or Set headers for requests created by a Sling.
@@ -38,6 +49,20 @@ Add or Set headers for requests created by a Sling.
 	s := sling.New().Base(baseUrl).Set("User-Agent", "Gophergram API Client")
 	req, err := s.New().Get("gophergram/list").Request()
 
+Use Del to remove a header set by a parent Sling. Use HeaderStruct to encode a
+header tagged struct as headers, and DecodeHeaders to decode response headers
+(e.g. rate limits) into one.
+
+	type RateLimit struct {
+	    Limit     int       `header:"X-RateLimit-Limit"`
+	    Remaining int       `header:"X-RateLimit-Remaining"`
+	    Reset     time.Time `header:"X-RateLimit-Reset,unix"`
+	}
+
+	resp, err := githubBase.New().Get(path).Del("X-Debug").ReceiveSuccess(issues)
+	rateLimit := new(RateLimit)
+	err = sling.DecodeHeaders(resp, rateLimit)
+
 # QueryStruct
 
 Define url parameter structs (https://godoc.org/github.com/google/go-querystring/query).
@@ -59,6 +84,21 @@ Use QueryStruct to encode a struct as query parameters on requests.
 	params := &IssueParams{Sort: "updated", State: "open"}
 	req, err := githubBase.New().Get(path).QueryStruct(params).Request()
 
+Use QueryParam to add a single query parameter, SetQueryParam to replace a
+parameter (including one set by a parent Sling or the base URL), or
+DelQueryParam to remove one. QueryValues adds url.Values, and QueryStruct also
+accepts url.Values, map[string]string, or types implementing QueryEncoder. Use
+QueryArrayFormat to choose how parameters with multiple values are encoded
+(ArrayRepeat, ArrayComma, ArrayBrackets, or ArrayIndexed). Parameters with a
+single value are sent as scalars, so use a key ending in "[]" (e.g. "ids[]")
+to send a one element array.
+
+	req, err := githubBase.New().Get(path).
+	    SetQueryParam("state", "closed").
+	    QueryValues(url.Values{"labels": {"bug", "ui"}}).
+	    QueryArrayFormat(sling.ArrayComma).
+	    Request()
+
 # Json Body
 
 Define JSON tagged structs (https://golang.org/pkg/encoding/json/).
@@ -83,6 +123,14 @@ Use BodyJSON to JSON encode a struct as the Body on requests.
 
 Requests will include an "application/json" Content-Type header.
 
+# XML Body
+
+Use BodyXML to XML encode a struct as the Body on requests. Requests will
+include an "application/xml" Content-Type header. Use XMLDecoder to decode XML
+responses, or SOAP envelope payloads and faults.
+
+	resp, err := base.New().Post(path).BodyXML(order).ResponseDecoder(sling.XMLDecoder{SOAP: true}).Receive(result, new(sling.SOAPFault))
+
 # Form Body
 
 Define url tagged structs (https://godoc.org/github.com/google/go-querystring/query).
@@ -100,6 +148,21 @@ Use BodyForm to form url encode a struct as the Body on requests.
 Requests will include an "application/x-www-form-urlencoded" Content-Type
 header.
 
+# Multipart Body
+
+Use BodyMultipart to stream form fields and files as a multipart/form-data
+Body on requests. Files are streamed, not buffered in memory.
+
+	req, err := base.New().Post("upload").BodyMultipart(
+	    sling.FormField("title", "gopher"),
+	    sling.FormStruct(params),
+	    sling.FilePath("image", "gopher.png", "image/png"),
+	    sling.FileReader("notes", "notes.txt", "text/plain", reader),
+	).Request()
+
+Requests will include a "multipart/form-data" Content-Type header with the
+part boundary.
+
 # Plain Body
 
 Use Body to set a plain io.Reader on requests created by a Sling.
@@ -137,6 +200,13 @@ is undesired.
 
 Recap: If you wish to extend a Sling, create a new child copy with New().
 
+Use Immutable() to create a Sling whose setters return modified copies, rather
+than modifying the Sling. An immutable base Sling can be stored (e.g. in a
+service struct) and shared between goroutines without calling New().
+
+	base := sling.New().Base(twitterApi).Client(authClient).Immutable()
+	tweetShowSling := base.Get("statuses/show.json").QueryStruct(params)
+
 # Receive
 
 Define a JSON struct to decode a type from 2XX success responses. Use
@@ -175,5 +245,231 @@ failureV for non-2XX responses.
 	fmt.Println(issues, githubError, resp, err)
 
 Pass a nil successV or failureV argument to skip JSON decoding into that value.
+
+Use ErrorOnFailure to return an *HTTPError for non-2XX responses. An HTTPError
+holds the status code, request method and URL, response headers, the start of
+the raw response body, and the decoded failureV value (if provided) or the
+error decoding it (e.g. a *ResponseTooLargeError). Use MapStatusError to map
+status codes to your own errors.
+
+	var ErrNotFound = errors.New("not found")
+	base := githubBase.New().ErrorOnFailure().MapStatusError(404, ErrNotFound)
+	resp, err := base.New().Get(path).Receive(issues, githubError)
+	errors.Is(err, ErrNotFound)
+
+By default, responses are JSON decoded, regardless of Content-Type. Use a
+NegotiatingDecoder to select a ResponseDecoder by the response Content-Type
+(JSON, XML, forms, text, or bytes) and send a matching Accept header.
+Responses with other Content-Types (e.g. an HTML error page from a proxy)
+return an *UnsupportedContentTypeError. Use RegisterMediaType to register
+decoders for more media types.
+
+	base := sling.New().ResponseDecoder(sling.NewNegotiatingDecoder())
+	csvBase := base.New().RegisterMediaType("text/csv", csvDecoder{})
+
+# Typed Receive
+
+Use the generic Receive function to decode success responses into a value of
+type T, without declaring values to decode into. Non-2XX responses return an
+*HTTPError whose failure value (an *E) can be read with Failure.
+
+	issues, resp, err := sling.Receive[[]Issue, GithubError](githubBase.New().Get(path))
+	if githubError, ok := sling.Failure[GithubError](err); ok {
+	    fmt.Println(githubError.Message)
+	}
+
+Use NewEndpoint to bind a method, path template, and request encoding once.
+
+	type IssueListParams struct {
+	    Owner string `path:"owner" url:"-"`
+	    Repo  string `path:"repo" url:"-"`
+	    State string `url:"state,omitempty"`
+	}
+
+	listIssues := sling.NewEndpoint[IssueListParams, []Issue, GithubError](githubBase, "GET", "repos/{owner}/{repo}/issues").Query()
+	issues, resp, err := listIssues.Do(ctx, IssueListParams{Owner: "golang", Repo: "go"})
+
+# Pagination
+
+Use Paginate to iterate over the items of a paginated API. A PageStrategy
+decodes each page and determines the request for the next page (LinkHeader,
+Cursor, Offset, or PageNumber). Each page request reuses the Sling's headers,
+query structs, and ResponseDecoder.
+
+	pages := sling.Paginate[Issue](githubBase.New().Get(path), sling.LinkHeader())
+	for issue, err := range pages.MaxItems(100).All() {
+	    if err != nil {
+	        return err
+	    }
+	    fmt.Println(issue.Title)
+	}
+
+# Streaming
+
+Use ReceiveStream to receive large or long-lived responses with an open Body,
+which the caller must close. Non-2XX responses are decoded into the failure
+value and return an *HTTPError. Use CopyResponse or SaveResponse to copy the
+Body to an io.Writer or a file with an optional progress callback.
+
+	resp, err := base.New().Get("artifacts/build.tar.gz").ReceiveStream(apiError)
+	if err != nil {
+	    return err
+	}
+	_, err = sling.SaveResponse("build.tar.gz", resp, func(written, total int64) {
+	    fmt.Printf("%d/%d bytes\n", written, total)
+	})
+
+# Server-Sent Events
+
+Use Events to iterate over Server-Sent Events (text/event-stream) sent with the
+Sling's headers and middleware. Streams reconnect with a Last-Event-ID header,
+honoring the server's retry field. Use Decode to decode event data with the
+Sling's ResponseDecoder.
+
+	for event, err := range base.New().Get("updates").Events(ctx) {
+	    if err != nil {
+	        return err
+	    }
+	    update := new(Update)
+	    if err := event.Decode(update); err != nil {
+	        return err
+	    }
+	}
+
+# NDJSON
+
+Use NDJSONSeq or NDJSONChan to stream items from an iterator or channel as a
+newline-delimited JSON (application/x-ndjson) request body. Use ReceiveNDJSON
+or ReceiveNDJSONFunc to decode newline-delimited JSON responses one record at a
+time.
+
+	resp, err := base.New().Post("import").BodyProvider(sling.NDJSONSeq(slices.Values(records))).ReceiveSuccess(nil)
+
+	for record, err := range sling.ReceiveNDJSON[Record](base.New().Get("export"), apiError) {
+	    if err != nil {
+	        return err
+	    }
+	    fmt.Println(record.ID)
+	}
+
+# Large JSON Arrays
+
+Use ReceiveArray or ReceiveArrayFunc to decode the elements of a large JSON
+array one at a time, so memory use stays flat. The array may be the top-level
+value or nested under a dot-separated path. Non-2XX responses are decoded into
+the failure value and return an *HTTPError.
+
+	for event, err := range sling.ReceiveArray[AuditEvent](base.New().Get("audit"), "data.items", apiError) {
+	    if err != nil {
+	        return err
+	    }
+	    fmt.Println(event.ID)
+	}
+
+# Middleware
+
+Use Use to wrap the http Client with Middleware, which child Slings inherit.
+Middleware is applied in order, so the first middleware sees requests first.
+Use OnRequest and OnResponse to inspect or modify requests after they are
+created and responses before they are decoded.
+
+	base := sling.New().Base(baseURL).Use(
+	    sling.RequestID("X-Request-Id", nil),
+	    sling.Logger(logger),
+	)
+
+# Context
+
+Use Context to set a context.Context for requests created by a Sling and its
+children. Use ReceiveContext to send a single request with a context.
+Cancelling the context aborts encoding the body, sending the request, and
+decoding the response.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := githubBase.New().Get(path).ReceiveContext(ctx, issues, githubError)
+
+# Retry
+
+Use Retry to retry requests which fail with network errors or retryable
+status codes (e.g. 503). By default, only idempotent methods are retried, with
+capped exponential backoff and full jitter, honoring Retry-After headers.
+Responses with a Retry-After beyond the MaxBackoff aren't retried. Request
+bodies are re-obtained from the BodyProvider for each attempt. If retries are
+exhausted, a *RetryError reports every attempt, along with the last response
+(if any).
+
+	policy := sling.DefaultRetryPolicy()
+	policy.MaxAttempts = 5
+	base := sling.New().Base("https://api.github.com/").Retry(policy)
+
+# Token Authentication
+
+Use BearerAuth to send a static bearer token. Use TokenSource to send tokens
+from a TokenSource which refreshes them, such as from an OAuth2 token endpoint.
+Tokens are cached by the Sling and its children and refreshed shortly before
+they expire. If a request is rejected with a 401 Unauthorized, the token is
+refreshed once (shared by concurrent requests) and the request is retried.
+
+	src := sling.TokenSourceFunc(func(ctx context.Context) (*sling.Token, error) {
+	    // fetch a token
+	    return &sling.Token{AccessToken: accessToken, Expiry: expiry}, nil
+	})
+	base := sling.New().Base("https://api.example.com/").TokenSource(src)
+
+Use DigestAuth to authorize requests with HTTP Digest Authentication (RFC
+7616). Requests which receive a Digest challenge are sent again with a
+response to it, and the challenge is cached for later requests.
+
+	device := sling.New().Base("http://analyzer.lab.local/").DigestAuth("admin", password)
+
+Use TokenAuth to add token authentication to any Doer as Middleware. The
+oauth2 package provides TokenSources for the OAuth2 client credentials,
+refresh token, and device authorization grants.
+
+# Request Signing
+
+Use Signer to sign each request after its URL, query, headers, and body are
+set. HMACSigner signs the method, path, query, chosen headers, and a SHA-256
+of the body with an HMAC-SHA256 key. Signers can read the body with HashBody,
+which re-obtains a copy from the BodyProvider rather than buffering it.
+
+	signer := &sling.HMACSigner{KeyID: "key-1", Key: secret, SignedHeaders: []string{"Host", "Content-Type"}}
+	base := sling.New().Base("https://partner.example.com/").Signer(signer)
+
+The sigv4 package signs requests with AWS Signature Version 4, as headers or
+as presigned URLs. The httpsig package signs requests and verifies responses
+with HTTP Message Signatures (RFC 9421).
+
+# Content Digests
+
+Use ContentDigest to set the Content-Digest header (RFC 9530) of request
+Bodies and VerifyDigest to verify response Bodies against their
+Content-Digest, Repr-Digest, or Content-MD5 header as they're read.
+Mismatched Bodies return a *DigestMismatchError. SetContentDigest and
+VerifyContentDigest set or verify a Content-Digest outside a Sling, such as in
+a Signer or response hook.
+
+	uploads := sling.New().Base("https://storage.example.com/").ContentDigest(sling.DigestSHA256).VerifyDigest()
+
+# Compression
+
+Use CompressBody to gzip or deflate request Bodies of at least a minimum size
+as they're sent. Streamed Bodies of unknown length are always compressed.
+
+	ingest := sling.New().Base("https://ingest.example.com/").CompressBody(sling.EncodingGzip, 4096)
+
+Receive and streamed receives (e.g. ReceiveStream) decompress gzip and
+deflate responses which the http Transport doesn't, limiting the
+decompression ratio (see MaxDecompressionRatio).
+
+# Response Limits
+
+Use MaxResponseBytes to limit how much of a response Body is read when
+decoding, returning a *ResponseTooLargeError for larger Bodies, and
+MaxDrainBytes to limit how much of the remainder is drained for connection
+reuse.
+
+	base := sling.New().Base("https://api.example.com/").MaxResponseBytes(10 << 20).MaxDrainBytes(64 << 10)
 */
 package sling
@@ -0,0 +1,125 @@
+package sling
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Receive creates a new HTTP request with the Sling properties and returns
+// the response. Success responses (2XX) are decoded into a T value with the
+// Sling's ResponseDecoder. Other responses return an *HTTPError whose Failure
+// is the response decoded into an *E (see Failure). The Sling is not
+// modified.
+func Receive[T, E any](s *Sling) (T, *http.Response, error) {
+	var success T
+	resp, err := s.New().ErrorOnFailure().Receive(&success, new(E))
+	return success, resp, err
+}
+
+// ReceiveSuccess is like Receive, but does not decode non-2XX responses.
+func ReceiveSuccess[T any](s *Sling) (T, *http.Response, error) {
+	var success T
+	resp, err := s.New().ErrorOnFailure().Receive(&success, nil)
+	return success, resp, err
+}
+
+// Failure returns the decoded failure value of an *HTTPError in err's chain
+// if it is an *E.
+func Failure[E any](err error) (*E, bool) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return nil, false
+	}
+	failure, ok := httpErr.Failure.(*E)
+	return failure, ok
+}
+
+// Endpoint is a typed API endpoint which sends Req values and receives Resp
+// values or Err failure values. An Endpoint binds the method, path template,
+// and request encoding once, so calls just provide a Req value.
+//
+//	type IssueListParams struct {
+//	    Owner string `path:"owner" url:"-"`
+//	    Repo  string `path:"repo" url:"-"`
+//	    State string `url:"state,omitempty"`
+//	}
+//
+//	listIssues := sling.NewEndpoint[IssueListParams, []Issue, GithubError](base, "GET", "repos/{owner}/{repo}/issues").Query()
+//	issues, resp, err := listIssues.Do(ctx, IssueListParams{Owner: "golang", Repo: "go"})
+type Endpoint[Req, Resp, Err any] struct {
+	sling  *Sling
+	method string
+	path   string
+	query  bool
+	body   func(Req) BodyProvider
+}
+
+// NewEndpoint returns an Endpoint which sends requests with the given method
+// to the path template resolved against the Sling's URL. Path template
+// variables (e.g. "repos/{owner}/{repo}") are filled from the fields of Req
+// with a matching "path" tag (see PathParams), as RFC 6570 URI templates.
+func NewEndpoint[Req, Resp, Err any](s *Sling, method, path string) *Endpoint[Req, Resp, Err] {
+	return &Endpoint[Req, Resp, Err]{
+		sling:  s.New(),
+		method: method,
+		path:   path,
+	}
+}
+
+// Query sets the Endpoint to encode Req values as url query parameters. Req
+// should be a url tagged struct. See
+// https://godoc.org/github.com/google/go-querystring/query for details.
+func (e *Endpoint[Req, Resp, Err]) Query() *Endpoint[Req, Resp, Err] {
+	e.query = true
+	return e
+}
+
+// BodyJSON sets the Endpoint to JSON encode Req values as the request Body.
+func (e *Endpoint[Req, Resp, Err]) BodyJSON() *Endpoint[Req, Resp, Err] {
+	return e.BodyProvider(func(req Req) BodyProvider {
+		return jsonBodyProvider{payload: req}
+	})
+}
+
+// BodyForm sets the Endpoint to url encode Req values as the request Body.
+func (e *Endpoint[Req, Resp, Err]) BodyForm() *Endpoint[Req, Resp, Err] {
+	return e.BodyProvider(func(req Req) BodyProvider {
+		return formBodyProvider{payload: req}
+	})
+}
+
+// BodyProvider sets a function which returns the BodyProvider for a Req
+// value.
+func (e *Endpoint[Req, Resp, Err]) BodyProvider(body func(Req) BodyProvider) *Endpoint[Req, Resp, Err] {
+	e.body = body
+	return e
+}
+
+// Sling returns a new Sling with the Endpoint properties for the Req value.
+// Returns any error expanding the path template.
+func (e *Endpoint[Req, Resp, Err]) Sling(req Req) (*Sling, error) {
+	s := e.sling.New().Path(e.path).PathParams(req)
+	if _, err := s.expandURL(); err != nil {
+		return nil, err
+	}
+	s.method = e.method
+	if e.query {
+		s = s.QueryStruct(req)
+	}
+	if e.body != nil {
+		s = s.BodyProvider(e.body(req))
+	}
+	return s, nil
+}
+
+// Do sends a request for the Req value and returns the decoded Resp value.
+// Non-2XX responses return an *HTTPError whose Failure is an *Err.
+func (e *Endpoint[Req, Resp, Err]) Do(ctx context.Context, req Req) (Resp, *http.Response, error) {
+	s, err := e.Sling(req)
+	if err != nil {
+		var zero Resp
+		return zero, nil, err
+	}
+	return Receive[Resp, Err](s.Context(ctx))
+}
@@ -0,0 +1,159 @@
+package sling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestReceive_generic(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/success", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"text": "Some text", "favorite_count": 24}`)
+	})
+	mux.HandleFunc("/failure", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		fmt.Fprint(w, `{"message": "Invalid argument", "code": 215}`)
+	})
+
+	base := New().Client(client).Base("http://example.com/")
+	model, resp, err := Receive[FakeModel, APIError](base.New().Get("success"))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("expected %d, got %d", 200, resp.StatusCode)
+	}
+	expectedModel := FakeModel{Text: "Some text", FavoriteCount: 24}
+	if model != expectedModel {
+		t.Errorf("expected %v, got %v", expectedModel, model)
+	}
+
+	_, _, err = Receive[FakeModel, APIError](base.New().Get("failure"))
+	apiError, ok := Failure[APIError](err)
+	if !ok {
+		t.Fatalf("expected an *HTTPError with an *APIError Failure, got %v", err)
+	}
+	expectedAPIError := &APIError{Message: "Invalid argument", Code: 215}
+	if !reflect.DeepEqual(expectedAPIError, apiError) {
+		t.Errorf("expected %v, got %v", expectedAPIError, apiError)
+	}
+
+	_, _, err = ReceiveSuccess[FakeModel](base.New().Get("failure"))
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Failure != nil {
+		t.Errorf("expected an *HTTPError without a Failure, got %v", err)
+	}
+
+	// the Sling is not modified
+	if base.errorOnFailure {
+		t.Errorf("expected generic helpers not to modify the Sling")
+	}
+}
+
+func TestReceive_genericNonDefaultDecoder(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<response><text>Some text</text></response>`)
+	})
+
+	s := New().Client(client).Get("http://example.com/xml").ResponseDecoder(XMLDecoder{})
+	model, _, err := ReceiveSuccess[FakeModel](s)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if model.Text != "Some text" {
+		t.Errorf("expected %s, got %s", "Some text", model.Text)
+	}
+}
+
+type issueParams struct {
+	Owner string `path:"owner" url:"-" json:"-"`
+	Repo  string `path:"repo" url:"-" json:"-"`
+	State string `url:"state,omitempty" json:"state,omitempty"`
+}
+
+func TestEndpoint(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/repos/golang/go/issues", func(w http.ResponseWriter, r *http.Request) {
+		assertMethod(t, "GET", r)
+		assertQuery(t, map[string]string{"state": "open"}, r)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"text": "a"}, {"text": "b"}]`)
+	})
+	mux.HandleFunc("/repos/golang/go/labels", func(w http.ResponseWriter, r *http.Request) {
+		assertMethod(t, "POST", r)
+		if r.Header.Get("Content-Type") != jsonContentType {
+			t.Errorf("expected %s, got %s", jsonContentType, r.Header.Get("Content-Type"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(422)
+		fmt.Fprint(w, `{"message": "Validation failed", "code": 422}`)
+	})
+
+	base := New().Client(client).Base("http://example.com/")
+	list := NewEndpoint[issueParams, []FakeModel, APIError](base, "GET", "repos/{owner}/{repo}/issues").Query()
+	models, _, err := list.Do(context.Background(), issueParams{Owner: "golang", Repo: "go", State: "open"})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	expected := []FakeModel{{Text: "a"}, {Text: "b"}}
+	if !reflect.DeepEqual(expected, models) {
+		t.Errorf("expected %v, got %v", expected, models)
+	}
+
+	create := NewEndpoint[*issueParams, FakeModel, APIError](base, "POST", "repos/{owner}/{repo}/labels").BodyJSON()
+	_, resp, err := create.Do(context.Background(), &issueParams{Owner: "golang", Repo: "go"})
+	if apiError, ok := Failure[APIError](err); !ok || apiError.Code != 422 {
+		t.Errorf("expected an *APIError Failure, got %v", err)
+	}
+	if resp.StatusCode != 422 {
+		t.Errorf("expected %d, got %d", 422, resp.StatusCode)
+	}
+
+	_, _, err = list.Do(context.Background(), issueParams{Owner: "golang"})
+	if err == nil {
+		t.Errorf("expected an error for missing path variable, got nil")
+	}
+}
+
+func TestExpandTemplate_pathParams(t *testing.T) {
+	params := struct {
+		Owner  string `path:"owner"`
+		Repo   string `path:"repo"`
+		Number int    `path:"number"`
+	}{"a b", "x/y", 7}
+	cases := []struct {
+		template string
+		expected string
+		err      bool
+	}{
+		{"repos/{owner}/{repo}/issues/{number}", "repos/a%20b/x%2Fy/issues/7", false},
+		{"plain/path", "plain/path", false},
+		{"repos/{missing}", "", true},
+		{"repos/{owner", "", true},
+	}
+	for _, c := range cases {
+		path, err := expandTemplate(c.template, []interface{}{params})
+		if (err != nil) != c.err {
+			t.Errorf("%s: expected error %v, got %v", c.template, c.err, err)
+		}
+		if path != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, path)
+		}
+	}
+
+	req, _ := New().Base("http://a.io/").Path("repos/a%20b/x%2Fy/issues").Request()
+	if expected := "http://a.io/repos/a%20b/x%2Fy/issues"; req.URL.String() != expected {
+		t.Errorf("expected %s, got %s", expected, req.URL.String())
+	}
+}
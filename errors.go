@@ -0,0 +1,112 @@
+package sling
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxErrorBodyBytes is the maximum number of bytes of a failure response Body
+// kept by an HTTPError.
+const maxErrorBodyBytes = 1 << 10
+
+// HTTPError is returned for non-2XX responses by Slings with ErrorOnFailure
+// set. Use errors.As to inspect it.
+type HTTPError struct {
+	// StatusCode is the response status code (e.g. 404).
+	StatusCode int
+	// Status is the response status (e.g. "404 Not Found").
+	Status string
+	// Method is the request method.
+	Method string
+	// URL is the request URL, with any password redacted.
+	URL string
+	// Header holds the response headers.
+	Header http.Header
+	// Body holds up to the first 1KiB of the raw response Body.
+	Body []byte
+	// Failure is the decoded failureV value, if one was provided and the
+	// response was decoded successfully.
+	Failure interface{}
+	// DecodeErr is the error decoding the response into failureV, if any
+	// (e.g. a *ResponseTooLargeError).
+	DecodeErr error
+	// err is the error mapped to the status code (see MapStatusError)
+	err error
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("sling: %s %s: %s", e.Method, e.URL, e.Status)
+	if e.err != nil {
+		msg += ": " + e.err.Error()
+	}
+	if e.DecodeErr != nil {
+		msg += ": decoding failure: " + e.DecodeErr.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error mapped to the response status code and the
+// DecodeErr, if any.
+func (e *HTTPError) Unwrap() []error {
+	var errs []error
+	if e.err != nil {
+		errs = append(errs, e.err)
+	}
+	if e.DecodeErr != nil {
+		errs = append(errs, e.DecodeErr)
+	}
+	return errs
+}
+
+// newHTTPError returns an HTTPError for the failure response. If failureV is
+// non-nil, the response is decoded into it. The response Body is read up to
+// maxErrorBodyBytes, but not closed.
+func newHTTPError(req *http.Request, resp *http.Response, decoder ResponseDecoder, failureV interface{}, statusErrors map[int]error) *HTTPError {
+	snippet := &limitedBuffer{max: maxErrorBodyBytes}
+	body := resp.Body
+	resp.Body = readCloser{Reader: io.TeeReader(body, snippet), Closer: body}
+	defer func() { resp.Body = body }()
+
+	herr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		Header:     resp.Header,
+		err:        statusErrors[resp.StatusCode],
+	}
+	if failureV != nil && resp.StatusCode != http.StatusNoContent && resp.ContentLength != 0 {
+		if err := decoder.Decode(resp, failureV); err != nil {
+			herr.DecodeErr = err
+		} else {
+			herr.Failure = failureV
+		}
+	}
+	io.CopyN(io.Discard, resp.Body, int64(snippet.max-len(snippet.buf)))
+	herr.Body = snippet.buf
+	return herr
+}
+
+// limitedBuffer is an io.Writer which keeps up to max bytes and discards the
+// rest.
+type limitedBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if n := b.max - len(b.buf); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		b.buf = append(b.buf, p[:n]...)
+	}
+	return len(p), nil
+}
+
+// readCloser combines an io.Reader and io.Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,121 @@
+package sling
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestErrorOnFailure(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/failure", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(400)
+		fmt.Fprintf(w, `{"message": "Invalid argument", "code": 215}`)
+	})
+
+	model := new(FakeModel)
+	apiError := new(APIError)
+	resp, err := New().Client(client).ErrorOnFailure().Get("http://example.com/failure").Receive(model, apiError)
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	if resp.StatusCode != 400 || httpErr.StatusCode != 400 {
+		t.Errorf("expected %d, got %d", 400, httpErr.StatusCode)
+	}
+	if httpErr.Method != "GET" || httpErr.URL != "http://example.com/failure" {
+		t.Errorf("expected GET http://example.com/failure, got %s %s", httpErr.Method, httpErr.URL)
+	}
+	if httpErr.Header.Get("X-Request-Id") != "abc" {
+		t.Errorf("expected response headers, got %v", httpErr.Header)
+	}
+	if expected := `{"message": "Invalid argument", "code": 215}`; string(httpErr.Body) != expected {
+		t.Errorf("expected %s, got %s", expected, httpErr.Body)
+	}
+	expectedAPIError := &APIError{Message: "Invalid argument", Code: 215}
+	if !reflect.DeepEqual(expectedAPIError, httpErr.Failure) {
+		t.Errorf("expected %v, got %v", expectedAPIError, httpErr.Failure)
+	}
+	if expected := "sling: GET http://example.com/failure: 400 Bad Request"; err.Error() != expected {
+		t.Errorf("expected %s, got %s", expected, err.Error())
+	}
+}
+
+func TestErrorOnFailure_noFailureV(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/failure", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+		fmt.Fprint(w, strings.Repeat("x", 2*maxErrorBodyBytes))
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	})
+	mux.HandleFunc("/success", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"text": "Some text"}`)
+	})
+
+	base := New().Client(client).Base("http://example.com/").ErrorOnFailure()
+	_, err := base.New().Get("failure").ReceiveSuccess(new(FakeModel))
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	if len(httpErr.Body) != maxErrorBodyBytes {
+		t.Errorf("expected Body snippet of %d bytes, got %d", maxErrorBodyBytes, len(httpErr.Body))
+	}
+	if httpErr.Failure != nil {
+		t.Errorf("expected nil Failure, got %v", httpErr.Failure)
+	}
+
+	_, err = base.New().Get("empty").ReceiveSuccess(new(FakeModel))
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 503 {
+		t.Errorf("expected a 503 *HTTPError, got %v", err)
+	}
+
+	model := new(FakeModel)
+	_, err = base.New().Get("success").ReceiveSuccess(model)
+	if err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if model.Text != "Some text" {
+		t.Errorf("expected %s, got %s", "Some text", model.Text)
+	}
+}
+
+func TestMapStatusError(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	})
+	mux.HandleFunc("/conflict", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+	})
+
+	errNotFound := errors.New("not found")
+	errConflict := errors.New("conflict")
+	parent := New().Client(client).Base("http://example.com/").ErrorOnFailure().MapStatusError(404, errNotFound)
+	child := parent.New().MapStatusError(409, errConflict)
+
+	_, err := child.New().Get("missing").Receive(nil, nil)
+	if !errors.Is(err, errNotFound) {
+		t.Errorf("expected error to wrap %v, got %v", errNotFound, err)
+	}
+	_, err = child.New().Get("conflict").Receive(nil, nil)
+	if !errors.Is(err, errConflict) {
+		t.Errorf("expected error to wrap %v, got %v", errConflict, err)
+	}
+	// status errors registered on a child should not be registered on the parent
+	_, err = parent.New().Get("conflict").Receive(nil, nil)
+	if errors.Is(err, errConflict) {
+		t.Errorf("expected parent error not to wrap %v", errConflict)
+	}
+}
@@ -0,0 +1,231 @@
+package sling
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	eventStreamContentType = "text/event-stream"
+	// defaultEventRetry is the reconnection delay until a server sends a
+	// retry field.
+	defaultEventRetry = 3 * time.Second
+	// maxEventLineBytes is the maximum length of an event stream line.
+	maxEventLineBytes = 1 << 20
+)
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is the last event ID of the stream when the event was dispatched.
+	ID string
+	// Type is the event type, "message" unless set by an event field.
+	Type string
+	// Data is the event data, with multiple data fields joined by newlines.
+	Data string
+	// Retry is the reconnection delay set by a retry field of the event, or
+	// zero.
+	Retry time.Duration
+	// decoder for the event data
+	decoder ResponseDecoder
+}
+
+// Decode decodes the event Data as JSON into the value pointed to by v, using
+// the ResponseDecoder of the Sling which received the event.
+func (e *Event) Decode(v interface{}) error {
+	decoder := e.decoder
+	if decoder == nil {
+		decoder = jsonDecoder{}
+	}
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{contentType: {jsonContentType}},
+		Body:          io.NopCloser(strings.NewReader(e.Data)),
+		ContentLength: int64(len(e.Data)),
+	}
+	return decoder.Decode(resp, v)
+}
+
+// Events returns an iterator over the Server-Sent Events (text/event-stream)
+// of the Sling's request, which is sent with the Sling's headers, Doer, and
+// middleware.
+//
+// When a connection ends or fails, the request is sent again after the
+// reconnection delay (3 seconds, unless set by a retry field) with a
+// Last-Event-ID header. Errors sending requests or reading events are
+// yielded and iteration continues with a reconnection, unless the loop
+// stops. A 204 No Content response ends iteration. Non-2XX responses (as an
+// *HTTPError), responses with another Content-Type (as an
+// *UnsupportedContentTypeError), and context errors are yielded last.
+func (s *Sling) Events(ctx context.Context) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		stream := &eventStream{sling: s, retry: defaultEventRetry}
+		for stream.connect(ctx, yield) {
+			if err := sleep(ctx, stream.retry); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// eventStream holds the state of an event stream across connections.
+type eventStream struct {
+	sling       *Sling
+	lastEventID string
+	retry       time.Duration
+}
+
+// connect sends a request for the event stream and yields its events.
+// Returns whether to reconnect.
+func (es *eventStream) connect(ctx context.Context, yield func(*Event, error) bool) bool {
+	s := es.sling.New().Set("Accept", eventStreamContentType).Set("Cache-Control", "no-cache")
+	if es.lastEventID != "" {
+		s = s.Set("Last-Event-ID", es.lastEventID)
+	}
+	req, err := s.RequestContext(ctx)
+	if err != nil {
+		yield(nil, err)
+		return false
+	}
+	resp, attempts, err := s.send(req)
+	if err != nil {
+		return es.fail(ctx, err, yield)
+	}
+	defer resp.Body.Close()
+
+	s.wrapResponseBody(req, resp)
+	s.decompressBody(req, resp)
+	if resp.StatusCode == http.StatusNoContent {
+		return false
+	}
+	if !isSuccess(resp.StatusCode) {
+		yield(nil, withAttempts(attempts, newHTTPError(req, resp, s.responseDecoder, nil, s.statusErrors)))
+		return false
+	}
+	header := resp.Header.Get(contentType)
+	if mediaType, _, _ := mime.ParseMediaType(header); mediaType != eventStreamContentType {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxExcerptBytes))
+		yield(nil, &UnsupportedContentTypeError{
+			ContentType: header,
+			StatusCode:  resp.StatusCode,
+			Excerpt:     excerpt,
+		})
+		return false
+	}
+
+	stopped, err := es.read(resp.Body, yield)
+	if stopped {
+		return false
+	}
+	if err != nil {
+		return es.fail(ctx, err, yield)
+	}
+	return true
+}
+
+// fail yields a connection error and returns whether to reconnect.
+func (es *eventStream) fail(ctx context.Context, err error, yield func(*Event, error) bool) bool {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		yield(nil, ctxErr)
+		return false
+	}
+	return yield(nil, err)
+}
+
+// read parses events from the event stream and yields them, returning
+// whether yield stopped iteration or any error reading the stream. An
+// incomplete event at the end of the stream is discarded.
+func (es *eventStream) read(r io.Reader, yield func(*Event, error) bool) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxEventLineBytes)
+	scanner.Split(scanEventLines)
+	// the last event ID is set when events are dispatched
+	id := es.lastEventID
+	var eventType string
+	var data strings.Builder
+	var retry time.Duration
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// dispatch the event
+			es.lastEventID = id
+			if data.Len() > 0 {
+				event := &Event{
+					ID:      es.lastEventID,
+					Type:    eventType,
+					Data:    strings.TrimSuffix(data.String(), "\n"),
+					Retry:   retry,
+					decoder: es.sling.responseDecoder,
+				}
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				if !yield(event, nil) {
+					return true, nil
+				}
+			}
+			eventType, retry = "", 0
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// comment
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				id = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 32); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				es.retry = retry
+			}
+		}
+	}
+	return false, scanner.Err()
+}
+
+// scanEventLines is a bufio.SplitFunc which splits event stream lines ending
+// in CRLF, LF, or CR.
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// a following LF may not have been read yet
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
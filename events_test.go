@@ -0,0 +1,167 @@
+package sling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	connections := 0
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		assertMethod(t, "GET", r)
+		if r.Header.Get("Accept") != eventStreamContentType {
+			t.Errorf("expected %s, got %s", eventStreamContentType, r.Header.Get("Accept"))
+		}
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("expected Sling headers to be sent")
+		}
+		connections++
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		switch connections {
+		case 1:
+			if id := r.Header.Get("Last-Event-ID"); id != "" {
+				t.Errorf("expected no Last-Event-ID, got %s", id)
+			}
+			fmt.Fprint(w, ": comment\nretry: 10\n\n")
+			fmt.Fprint(w, "id: 1\nevent: update\ndata: {\"text\": \"a\",\ndata: \"favorite_count\": 1}\n\n")
+			// incomplete events are discarded
+			fmt.Fprint(w, "id: 2\ndata: incomplete")
+		case 2:
+			if id := r.Header.Get("Last-Event-ID"); id != "1" {
+				t.Errorf("expected Last-Event-ID %s, got %s", "1", id)
+			}
+			fmt.Fprint(w, "data:b\r\n\r\ndata\rdata: c\r\r")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	s := New().Client(client).Get("http://example.com/events").SetBasicAuth("user", "pass")
+	var events []*Event
+	for event, err := range s.Events(context.Background()) {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		event.decoder = nil
+		events = append(events, event)
+	}
+	expected := []*Event{
+		{ID: "1", Type: "update", Data: "{\"text\": \"a\",\n\"favorite_count\": 1}"},
+		{ID: "1", Type: "message", Data: "b"},
+		{ID: "1", Type: "message", Data: "\nc"},
+	}
+	if !reflect.DeepEqual(expected, events) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	if connections != 3 {
+		t.Errorf("expected %d connections, got %d", 3, connections)
+	}
+	var model FakeModel
+	if err := events[0].Decode(&model); err != nil || model.FavoriteCount != 1 {
+		t.Errorf("expected event data to be decoded, got %v %v", model, err)
+	}
+}
+
+func TestEvents_decode(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"text\": \"a\"}\n\n")
+	})
+
+	s := New().Client(client).Get("http://example.com/events").ResponseDecoder(NewNegotiatingDecoder())
+	for event, err := range s.Events(context.Background()) {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		var model FakeModel
+		if err := event.Decode(&model); err != nil || model.Text != "a" {
+			t.Errorf("expected event data to be decoded, got %v %v", model, err)
+		}
+		break
+	}
+}
+
+func TestEvents_errors(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/unauthorized", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	})
+	mux.HandleFunc("/html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html></html>")
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 10\n\n")
+	})
+
+	base := New().Client(client).Base("http://example.com/")
+	errs := collectEventErrors(base.New().Get("unauthorized").Events(context.Background()))
+	var httpErr *HTTPError
+	if len(errs) != 1 || !errors.As(errs[0], &httpErr) || httpErr.StatusCode != 401 {
+		t.Errorf("expected a 401 *HTTPError, got %v", errs)
+	}
+
+	errs = collectEventErrors(base.New().Get("html").Events(context.Background()))
+	var ctErr *UnsupportedContentTypeError
+	if len(errs) != 1 || !errors.As(errs[0], &ctErr) || string(ctErr.Excerpt) != "<html></html>" {
+		t.Errorf("expected an *UnsupportedContentTypeError, got %v", errs)
+	}
+
+	// streams reconnect until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	errs = collectEventErrors(base.New().Get("stream").Events(ctx))
+	if len(errs) != 1 || !errors.Is(errs[0], context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, errs)
+	}
+}
+
+func TestScanEventLines(t *testing.T) {
+	cases := []struct {
+		data     string
+		atEOF    bool
+		advance  int
+		expected string
+	}{
+		{"a\nb", false, 2, "a"},
+		{"a\r\nb", false, 3, "a"},
+		{"a\rb", false, 2, "a"},
+		{"a\r", false, 0, ""},
+		{"a\r", true, 2, "a"},
+		{"a", false, 0, ""},
+		{"a", true, 1, "a"},
+	}
+	for _, c := range cases {
+		advance, token, _ := scanEventLines([]byte(c.data), c.atEOF)
+		if advance != c.advance || string(token) != c.expected {
+			t.Errorf("%q: expected (%d, %q), got (%d, %q)", c.data, c.advance, c.expected, advance, token)
+		}
+	}
+}
+
+// collectEventErrors returns the errors yielded by an event iterator,
+// failing on events.
+func collectEventErrors(events func(func(*Event, error) bool)) []error {
+	var errs []error
+	for event, err := range events {
+		if event != nil {
+			errs = append(errs, fmt.Errorf("unexpected event %v", strings.TrimSpace(event.Data)))
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
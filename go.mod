@@ -1,5 +1,5 @@
 module github.com/dghubble/sling
 
-go 1.19
+go 1.23
 
 require github.com/google/go-querystring v1.2.0
@@ -0,0 +1,326 @@
+package sling
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// headerField is a struct field tagged with "header".
+type headerField struct {
+	// canonical header key
+	key   string
+	index []int
+	// tag options
+	omitEmpty bool
+	comma     bool
+	unix      bool
+}
+
+// headerFields returns the header fields of the struct type, including
+// fields of embedded structs. Fields are tagged `header:"Key,options"`, with
+// options "omitempty", "comma" (join or split values with commas), and
+// "unix" (times as Unix seconds). Untagged fields use the field name as the
+// key and fields tagged "-" are skipped.
+func headerFields(rt reflect.Type) []headerField {
+	var fields []headerField
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("header")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				for _, f := range headerFields(ft) {
+					f.index = append([]int{i}, f.index...)
+					fields = append(fields, f)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		f := headerField{key: http.CanonicalHeaderKey(name), index: []int{i}}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "comma":
+				f.comma = true
+			case "unix":
+				f.unix = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// structValue returns the struct value pointed to by v, or false if v is not
+// a struct or a non-nil pointer to a struct.
+func structValue(v interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return rv, false
+		}
+		rv = rv.Elem()
+	}
+	return rv, rv.Kind() == reflect.Struct
+}
+
+// encodeHeaders encodes the header tagged struct v as header values.
+func encodeHeaders(v interface{}) (http.Header, error) {
+	rv, ok := structValue(v)
+	if !ok {
+		return nil, fmt.Errorf("sling: HeaderStruct expects a struct, got %T", v)
+	}
+	header := make(http.Header)
+	for _, field := range headerFields(rv.Type()) {
+		fv, err := rv.FieldByIndexErr(field.index)
+		if err != nil {
+			// nil embedded struct pointer
+			continue
+		}
+		if field.omitEmpty && fv.IsZero() {
+			continue
+		}
+		for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+		var values []string
+		if (fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array) && !fv.Type().Implements(textMarshalerType) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			if field.omitEmpty && fv.Len() == 0 {
+				continue
+			}
+			for i := 0; i < fv.Len(); i++ {
+				value, err := formatHeaderValue(fv.Index(i), field)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			}
+			if field.comma {
+				values = []string{strings.Join(values, ", ")}
+			}
+		} else {
+			value, err := formatHeaderValue(fv, field)
+			if err != nil {
+				return nil, err
+			}
+			values = []string{value}
+		}
+		header[field.key] = append(header[field.key], values...)
+	}
+	return header, nil
+}
+
+// formatHeaderValue formats a single header value.
+func formatHeaderValue(v reflect.Value, field headerField) (string, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if field.unix {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+		return t.UTC().Format(http.TimeFormat), nil
+	}
+	if v.Type() == durationType {
+		return strconv.FormatInt(int64(v.Interface().(time.Duration)/time.Second), 10), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// DecodeHeaders decodes the response headers into the header tagged struct
+// pointed to by v, such as rate limit counters or pagination totals. Fields
+// are tagged `header:"Key,options"` as in HeaderStruct. Strings, numbers,
+// bools, times (HTTP-dates, or Unix seconds with the "unix" option),
+// durations (seconds), encoding.TextUnmarshalers, and slices of these (from
+// multiple header values, or comma-separated values with the "comma"
+// option) are supported. Fields for missing headers are left unchanged.
+func DecodeHeaders(resp *http.Response, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("sling: DecodeHeaders expects a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	for _, field := range headerFields(rv.Type()) {
+		values := resp.Header.Values(field.key)
+		if len(values) == 0 {
+			continue
+		}
+		fv, ok := fieldByIndexAlloc(rv, field.index)
+		if !ok {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			fv = fv.Elem()
+		}
+		var err error
+		if fv.Kind() == reflect.Slice && !reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) && fv.Type().Elem().Kind() != reflect.Uint8 {
+			if field.comma {
+				var split []string
+				for _, value := range values {
+					for _, item := range strings.Split(value, ",") {
+						split = append(split, strings.TrimSpace(item))
+					}
+				}
+				values = split
+			}
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for i, value := range values {
+				if err = parseHeaderValue(slice.Index(i), value, field); err != nil {
+					break
+				}
+			}
+			fv.Set(slice)
+		} else {
+			err = parseHeaderValue(fv, values[0], field)
+		}
+		if err != nil {
+			return fmt.Errorf("sling: decoding header %s: %w", field.key, err)
+		}
+	}
+	return nil
+}
+
+// fieldByIndexAlloc returns the nested field, allocating nil embedded struct
+// pointers. Returns false if a nil embedded struct pointer can't be set
+// because its type is unexported.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// parseHeaderValue parses a single header value into v.
+func parseHeaderValue(v reflect.Value, value string, field headerField) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		var t time.Time
+		var err error
+		if field.unix {
+			var sec int64
+			sec, err = strconv.ParseInt(value, 10, 64)
+			t = time.Unix(sec, 0)
+		} else {
+			t, err = http.ParseTime(value)
+		}
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		sec, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(sec * float64(time.Second)))
+		return nil
+	case reflect.PointerTo(v.Type()).Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(value))
+			return nil
+		}
+		fallthrough
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
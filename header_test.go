@@ -0,0 +1,145 @@
+package sling
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeHeaders struct {
+	RequestID string        `header:"x-request-id"`
+	Tenant    *string       `header:"X-Tenant,omitempty"`
+	Priority  int           `header:"X-Priority,omitempty"`
+	Since     time.Time     `header:"If-Modified-Since,omitempty"`
+	Reset     time.Time     `header:"X-Reset,unix,omitempty"`
+	Timeout   time.Duration `header:"X-Timeout,omitempty"`
+	Tags      []string      `header:"X-Tag,omitempty"`
+	Accept    []string      `header:"Accept,comma,omitempty"`
+	Address   net.IP        `header:"X-Forwarded-For,omitempty"`
+	Debug     bool          `header:"X-Debug,omitempty"`
+	Ignored   string        `header:"-"`
+	fakeEmbeddedHeaders
+}
+
+type fakeEmbeddedHeaders struct {
+	Version float64 `header:"X-Version,omitempty"`
+}
+
+func TestHeaderStruct(t *testing.T) {
+	tenant := "acme"
+	since := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	headers := &fakeHeaders{
+		RequestID:           "abc",
+		Tenant:              &tenant,
+		Priority:            2,
+		Since:               since,
+		Reset:               since,
+		Timeout:             30 * time.Second,
+		Tags:                []string{"a", "b"},
+		Accept:              []string{"application/json", "text/plain"},
+		Address:             net.ParseIP("10.0.0.1"),
+		Debug:               true,
+		Ignored:             "x",
+		fakeEmbeddedHeaders: fakeEmbeddedHeaders{Version: 1.5},
+	}
+	s := New().Set("X-Priority", "1").Set("Authorization", "token").HeaderStruct(headers)
+	req, err := s.Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	expected := http.Header{
+		"Authorization":     {"token"},
+		"X-Request-Id":      {"abc"},
+		"X-Tenant":          {"acme"},
+		"X-Priority":        {"2"},
+		"If-Modified-Since": {"Wed, 21 Oct 2015 07:28:00 GMT"},
+		"X-Reset":           {"1445412480"},
+		"X-Timeout":         {"30"},
+		"X-Tag":             {"a", "b"},
+		"Accept":            {"application/json, text/plain"},
+		"X-Forwarded-For":   {"10.0.0.1"},
+		"X-Debug":           {"true"},
+		"X-Version":         {"1.5"},
+	}
+	if !reflect.DeepEqual(expected, req.Header) {
+		t.Errorf("not DeepEqual: expected %v, got %v", expected, req.Header)
+	}
+
+	// omitempty fields are skipped and later Set, Add, or Del calls apply
+	s = New().HeaderStruct(fakeHeaders{}).Add("X-Tag", "c").Del("X-Request-Id")
+	expected = http.Header{"X-Tag": {"c"}}
+	if !reflect.DeepEqual(expected, s.header) {
+		t.Errorf("not DeepEqual: expected %v, got %v", expected, s.header)
+	}
+
+	_, err = New().HeaderStruct("not a struct").Request()
+	if err == nil || !strings.Contains(err.Error(), "HeaderStruct expects a struct") {
+		t.Errorf("expected a HeaderStruct error, got %v", err)
+	}
+}
+
+type fakeRateLimit struct {
+	Limit     int           `header:"X-RateLimit-Limit"`
+	Remaining *int          `header:"X-RateLimit-Remaining"`
+	Reset     time.Time     `header:"X-RateLimit-Reset,unix"`
+	Date      time.Time     `header:"Date"`
+	Retry     time.Duration `header:"Retry-After"`
+	Links     []string      `header:"Link"`
+	Vary      []string      `header:"Vary,comma"`
+	Address   net.IP        `header:"X-Forwarded-For"`
+	Missing   string        `header:"X-Missing"`
+	fakeEmbeddedHeaders
+	// nil embedded pointers to unexported types are skipped
+	*fakeUnexportedHeaders
+}
+
+type fakeUnexportedHeaders struct {
+	Vary string `header:"Vary"`
+}
+
+func TestDecodeHeaders(t *testing.T) {
+	resp := &http.Response{Header: http.Header{
+		"X-Ratelimit-Limit":     {"5000"},
+		"X-Ratelimit-Remaining": {"4999"},
+		"X-Ratelimit-Reset":     {"1445412480"},
+		"Date":                  {"Wed, 21 Oct 2015 07:28:00 GMT"},
+		"Retry-After":           {"120"},
+		"Link":                  {"<a>; rel=next", "<b>; rel=last"},
+		"Vary":                  {"Accept, Accept-Encoding", "Origin"},
+		"X-Forwarded-For":       {"10.0.0.1"},
+		"X-Version":             {"2"},
+	}}
+	rateLimit := &fakeRateLimit{Missing: "unchanged"}
+	if err := DecodeHeaders(resp, rateLimit); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	remaining := 4999
+	date := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	expected := &fakeRateLimit{
+		Limit:               5000,
+		Remaining:           &remaining,
+		Reset:               time.Unix(1445412480, 0),
+		Date:                date,
+		Retry:               2 * time.Minute,
+		Links:               []string{"<a>; rel=next", "<b>; rel=last"},
+		Vary:                []string{"Accept", "Accept-Encoding", "Origin"},
+		Address:             net.ParseIP("10.0.0.1"),
+		Missing:             "unchanged",
+		fakeEmbeddedHeaders: fakeEmbeddedHeaders{Version: 2},
+	}
+	if !reflect.DeepEqual(expected, rateLimit) {
+		t.Errorf("not DeepEqual: expected %+v, got %+v", expected, rateLimit)
+	}
+
+	resp.Header.Set("X-RateLimit-Limit", "unlimited")
+	err := DecodeHeaders(resp, rateLimit)
+	if err == nil || !strings.Contains(err.Error(), "X-Ratelimit-Limit") {
+		t.Errorf("expected a decoding error, got %v", err)
+	}
+	if err := DecodeHeaders(resp, fakeRateLimit{}); err == nil {
+		t.Errorf("expected an error decoding into a non-pointer, got nil")
+	}
+}
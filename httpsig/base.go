@@ -0,0 +1,183 @@
+package httpsig
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// message is a request, or a response and the request it answers.
+type message struct {
+	req  *http.Request
+	resp *http.Response
+}
+
+// header returns the message header fields.
+func (m *message) header() http.Header {
+	if m.resp != nil {
+		return m.resp.Header
+	}
+	return m.req.Header
+}
+
+// signatureBase returns the signature base (RFC 9421 Section 2.5) of the
+// covered components and signature parameters.
+func signatureBase(m *message, items []sfItem, params []sfParam) (string, error) {
+	var b strings.Builder
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		name, ok := item.value.(string)
+		if !ok {
+			return "", fmt.Errorf("httpsig: component identifier must be a string, got %v", item.value)
+		}
+		identifier := serializeBareItem(name) + serializeParams(item.params)
+		if seen[identifier] {
+			return "", fmt.Errorf("httpsig: duplicate component %s", identifier)
+		}
+		seen[identifier] = true
+		value, err := componentValue(m, name, item.params)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(identifier + ": " + value + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + serializeInnerList(items, params))
+	return b.String(), nil
+}
+
+// componentValue returns the value of a derived component or header field.
+func componentValue(m *message, name string, params []sfParam) (string, error) {
+	if !strings.HasPrefix(name, "@") {
+		return fieldValue(m, name)
+	}
+	req := m.req
+	if name == "@status" {
+		if m.resp == nil {
+			return "", fmt.Errorf("httpsig: @status is only defined for responses")
+		}
+		return strconv.Itoa(m.resp.StatusCode), nil
+	}
+	if req == nil {
+		return "", fmt.Errorf("httpsig: component %s requires the request", name)
+	}
+	u := req.URL
+	switch name {
+	case "@method":
+		return req.Method, nil
+	case "@target-uri":
+		target := *u
+		if target.Host == "" {
+			target.Host = req.Host
+		}
+		if target.Scheme == "" {
+			target.Scheme = scheme(req)
+		}
+		return target.String(), nil
+	case "@authority":
+		return authority(req), nil
+	case "@scheme":
+		return scheme(req), nil
+	case "@request-target":
+		return u.RequestURI(), nil
+	case "@path":
+		if path := u.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + u.RawQuery, nil
+	case "@query-param":
+		paramName, ok := param(params, "name").(string)
+		if !ok {
+			return "", fmt.Errorf("httpsig: @query-param requires a name parameter")
+		}
+		values, err := url.ParseQuery(u.RawQuery)
+		if err != nil {
+			return "", err
+		}
+		// names are compared in their encoded form
+		var matched []string
+		for key, value := range values {
+			if encodeQueryParam(key) == paramName {
+				matched = append(matched, value...)
+			}
+		}
+		if len(matched) != 1 {
+			return "", fmt.Errorf("httpsig: query param %q is missing or repeated", paramName)
+		}
+		return encodeQueryParam(matched[0]), nil
+	}
+	return "", fmt.Errorf("httpsig: unsupported derived component %s", name)
+}
+
+// encodeQueryParam percent-encodes a decoded query parameter name or value
+// with the application/x-www-form-urlencoded percent-encode set, encoding
+// spaces as "%20" rather than "+" (RFC 9421 Section 2.2.8).
+func encodeQueryParam(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("*-._", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// fieldValue returns the header field values, trimmed and joined by ", ".
+func fieldValue(m *message, name string) (string, error) {
+	values := m.header().Values(name)
+	if len(values) == 0 && m.resp == nil {
+		// request fields which the http Transport sets
+		switch name {
+		case "host":
+			values = []string{authority(m.req)}
+		case "content-length":
+			if m.req.ContentLength > 0 || m.req.Body == nil || m.req.Body == http.NoBody {
+				values = []string{strconv.FormatInt(m.req.ContentLength, 10)}
+			}
+		}
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("httpsig: covered field %q is missing", name)
+	}
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// scheme returns the lowercase request scheme.
+func scheme(req *http.Request) string {
+	if req.URL.Scheme != "" {
+		return strings.ToLower(req.URL.Scheme)
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// authority returns the lowercase request host, without a default port.
+func authority(req *http.Request) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host = strings.ToLower(host)
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if port == "80" && scheme(req) == "http" || port == "443" && scheme(req) == "https" {
+			if strings.Contains(h, ":") {
+				return "[" + h + "]"
+			}
+			return h
+		}
+	}
+	return host
+}
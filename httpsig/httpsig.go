@@ -0,0 +1,272 @@
+/*
+Package httpsig signs and verifies HTTP Message Signatures (RFC 9421).
+
+A Signer signs requests created by a Sling (see Sling.Signer) over derived
+components (e.g. "@method", "@target-uri", "@authority", "@path", "@query")
+and header fields, and sets the Signature-Input and Signature headers. If the
+"content-digest" field is covered, the Content-Digest header (RFC 9530) is
+computed from the body first.
+
+	signer := &httpsig.Signer{
+	    Algorithm:  httpsig.Ed25519,
+	    Key:        privateKey,
+	    KeyID:      "partner-key-1",
+	    Components: []string{"@method", "@target-uri", "content-type", "content-digest"},
+	}
+	partner := sling.New().Base("https://partner.example.com/").Signer(signer)
+
+A Verifier verifies signed responses (and requests, for servers). Use
+VerifyResponse as a Sling response hook.
+
+	verifier := &httpsig.Verifier{
+	    Algorithm:  httpsig.Ed25519,
+	    Key:        partnerPublicKey,
+	    Components: []string{"@status", "content-digest"},
+	}
+	partner = partner.OnResponse(verifier.VerifyResponse)
+
+HMAC-SHA256, Ed25519, ECDSA P-256 SHA-256, and RSA-PSS SHA-512 keys are
+supported.
+*/
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dghubble/sling"
+)
+
+// Algorithm is an HTTP Signature Algorithm (RFC 9421 Section 6.2).
+type Algorithm string
+
+// Supported algorithms.
+const (
+	// HMACSHA256 uses a []byte shared secret.
+	HMACSHA256 Algorithm = "hmac-sha256"
+	// Ed25519 uses an ed25519.PrivateKey or ed25519.PublicKey.
+	Ed25519 Algorithm = "ed25519"
+	// ECDSAP256SHA256 uses an *ecdsa.PrivateKey or *ecdsa.PublicKey on the
+	// P-256 curve.
+	ECDSAP256SHA256 Algorithm = "ecdsa-p256-sha256"
+	// RSAPSSSHA512 uses an *rsa.PrivateKey or *rsa.PublicKey.
+	RSAPSSSHA512 Algorithm = "rsa-pss-sha512"
+)
+
+// defaultLabel is the label of signatures if none is set.
+const defaultLabel = "sig"
+
+// ErrInvalidSignature is returned when a signature doesn't match the
+// signature base.
+var ErrInvalidSignature = errors.New("httpsig: invalid signature")
+
+// Signer is a sling.Signer which signs requests with HTTP Message
+// Signatures.
+type Signer struct {
+	// Algorithm is the signature algorithm of the Key.
+	Algorithm Algorithm
+	// Key is the signing key (see Algorithm).
+	Key interface{}
+	// KeyID is sent as the keyid parameter, if set.
+	KeyID string
+	// Label identifies the signature in the Signature-Input and Signature
+	// headers. Defaults to "sig".
+	Label string
+	// Components are the covered component identifiers, derived components
+	// (e.g. "@method") or header fields (e.g. "content-type"), with any
+	// parameters (e.g. `@query-param;name="id"`). Defaults to "@method" and
+	// "@target-uri".
+	Components []string
+	// DigestAlgorithm is the Content-Digest algorithm, "sha-256" (default)
+	// or "sha-512".
+	DigestAlgorithm string
+	// Expires sets the expires parameter to the created time plus Expires,
+	// if non-zero.
+	Expires time.Duration
+	// Tag is sent as the tag parameter, if set.
+	Tag string
+	// Now returns the created time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Sign adds the signature of the request to its Signature-Input and
+// Signature headers, after setting Content-Digest if it is covered.
+func (s *Signer) Sign(req *http.Request) error {
+	components := s.Components
+	if len(components) == 0 {
+		components = []string{"@method", "@target-uri"}
+	}
+	items, err := parseComponents(components)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.value == "content-digest" && req.Header.Get("Content-Digest") == "" {
+			algorithm := s.DigestAlgorithm
+			if algorithm == "" {
+				algorithm = sling.DigestSHA256
+			}
+			if err := sling.SetContentDigest(req, algorithm); err != nil {
+				return fmt.Errorf("httpsig: %w", err)
+			}
+		}
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	created := now().Unix()
+	params := []sfParam{{name: "created", value: created}}
+	if s.Expires != 0 {
+		params = append(params, sfParam{name: "expires", value: created + int64(s.Expires/time.Second)})
+	}
+	if s.KeyID != "" {
+		params = append(params, sfParam{name: "keyid", value: s.KeyID})
+	}
+	if s.Tag != "" {
+		params = append(params, sfParam{name: "tag", value: s.Tag})
+	}
+
+	base, err := signatureBase(&message{req: req}, items, params)
+	if err != nil {
+		return err
+	}
+	signature, err := sign(s.Algorithm, s.Key, []byte(base))
+	if err != nil {
+		return err
+	}
+	label := s.Label
+	if label == "" {
+		label = defaultLabel
+	}
+	addDictionaryMember(req.Header, "Signature-Input", label+"="+serializeInnerList(items, params))
+	addDictionaryMember(req.Header, "Signature", label+"="+serializeBareItem(signature))
+	return nil
+}
+
+// addDictionaryMember adds the member to the dictionary header field.
+func addDictionaryMember(header http.Header, key, member string) {
+	if existing := header.Get(key); existing != "" {
+		member = existing + ", " + member
+	}
+	header.Set(key, member)
+}
+
+// sign signs the signature base with the key.
+func sign(alg Algorithm, key interface{}, base []byte) ([]byte, error) {
+	switch alg {
+	case HMACSHA256:
+		if secret, ok := key.([]byte); ok {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(base)
+			return mac.Sum(nil), nil
+		}
+	case Ed25519:
+		if priv, ok := key.(ed25519.PrivateKey); ok {
+			return ed25519.Sign(priv, base), nil
+		}
+	case ECDSAP256SHA256:
+		if priv, ok := key.(*ecdsa.PrivateKey); ok && priv.Curve == elliptic.P256() {
+			digest := sha256.Sum256(base)
+			r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+			if err != nil {
+				return nil, err
+			}
+			// r and s as fixed-size big-endian integers
+			signature := make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+			return signature, nil
+		}
+	case RSAPSSSHA512:
+		if priv, ok := key.(*rsa.PrivateKey); ok {
+			digest := sha512.Sum512(base)
+			return rsa.SignPSS(rand.Reader, priv, crypto.SHA512, digest[:], &rsa.PSSOptions{SaltLength: 64})
+		}
+	default:
+		return nil, fmt.Errorf("httpsig: unsupported algorithm %q", alg)
+	}
+	return nil, fmt.Errorf("httpsig: invalid %s signing key %T", alg, key)
+}
+
+// verify verifies the signature of the signature base with the key.
+func verify(alg Algorithm, key interface{}, base, signature []byte) error {
+	switch alg {
+	case HMACSHA256:
+		secret, ok := key.([]byte)
+		if !ok {
+			break
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(base)
+		return validSignature(hmac.Equal(mac.Sum(nil), signature))
+	case Ed25519:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			break
+		}
+		return validSignature(ed25519.Verify(pub, base, signature))
+	case ECDSAP256SHA256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			break
+		}
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		digest := sha256.Sum256(base)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return validSignature(ecdsa.Verify(pub, digest[:], r, s))
+	case RSAPSSSHA512:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+		digest := sha512.Sum512(base)
+		return validSignature(rsa.VerifyPSS(pub, crypto.SHA512, digest[:], signature, &rsa.PSSOptions{SaltLength: 64}) == nil)
+	default:
+		return fmt.Errorf("httpsig: unsupported algorithm %q", alg)
+	}
+	return fmt.Errorf("httpsig: invalid %s verification key %T", alg, key)
+}
+
+func validSignature(valid bool) error {
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// parseComponents parses component identifiers with optional parameters
+// (e.g. `@query-param;name="id"`).
+func parseComponents(components []string) ([]sfItem, error) {
+	items := make([]sfItem, len(components))
+	for i, component := range components {
+		name, rest, _ := strings.Cut(component, ";")
+		p := &sfParser{s: ";" + rest}
+		if rest == "" {
+			p.s = ""
+		}
+		params, err := p.params()
+		if err != nil || p.s != "" {
+			return nil, fmt.Errorf("httpsig: invalid component identifier %q", component)
+		}
+		items[i] = sfItem{value: strings.ToLower(strings.TrimSpace(name)), params: params}
+	}
+	return items, nil
+}
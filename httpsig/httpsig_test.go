@@ -0,0 +1,335 @@
+package httpsig
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dghubble/sling"
+)
+
+// RFC 9421 Appendix B test keys and signing time.
+var (
+	testSharedSecret, _ = base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	testKeyEd25519      = mustParsePKCS8("MC4CAQAwBQYDK2VwBCIEIJ+DYvh6SEqVTm50DFtMDoQikTmiCqirVv9mWG9qfSnF").(ed25519.PrivateKey)
+	testCreated         = func() time.Time { return time.Unix(1618884473, 0) }
+)
+
+func mustParsePKCS8(der string) interface{} {
+	b, _ := base64.StdEncoding.DecodeString(der)
+	key, err := x509.ParsePKCS8PrivateKey(b)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// testRequest returns the RFC 9421 Appendix B.2 test request.
+func testRequest(signer sling.Signer) *sling.Sling {
+	return sling.New().Post("http://example.com/foo?param=Value&Pet=dog").
+		Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT").
+		Set("Content-Type", "application/json").
+		Body(strings.NewReader(`{"hello": "world"}`)).
+		Signer(signer)
+}
+
+func TestSigner_vectors(t *testing.T) {
+	cases := []struct {
+		name          string
+		signer        *Signer
+		expectedInput string
+		expected      string
+	}{
+		// RFC 9421 Appendix B.2.5
+		{"hmac-sha256", &Signer{
+			Algorithm:  HMACSHA256,
+			Key:        testSharedSecret,
+			KeyID:      "test-shared-secret",
+			Label:      "sig-b25",
+			Components: []string{"date", "@authority", "content-type"},
+			Now:        testCreated,
+		}, `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`,
+			"sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:"},
+		// RFC 9421 Appendix B.2.6
+		{"ed25519", &Signer{
+			Algorithm:  Ed25519,
+			Key:        testKeyEd25519,
+			KeyID:      "test-key-ed25519",
+			Label:      "sig-b26",
+			Components: []string{"date", "@method", "@path", "@authority", "content-type", "content-length"},
+			Now:        testCreated,
+		}, `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`,
+			"sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:"},
+	}
+	for _, c := range cases {
+		req, err := testRequest(c.signer).Request()
+		if err != nil {
+			t.Fatalf("%s: expected nil, got %v", c.name, err)
+		}
+		if input := req.Header.Get("Signature-Input"); input != c.expectedInput {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expectedInput, input)
+		}
+		if signature := req.Header.Get("Signature"); signature != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, signature)
+		}
+	}
+}
+
+func TestSigner_contentDigest(t *testing.T) {
+	cases := []struct {
+		algorithm string
+		expected  string
+	}{
+		// RFC 9530 Appendix B
+		{"", "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:"},
+		{"sha-512", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"},
+	}
+	for _, c := range cases {
+		signer := &Signer{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"content-digest"}, DigestAlgorithm: c.algorithm}
+		req, err := testRequest(signer).Request()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if digest := req.Header.Get("Content-Digest"); digest != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, digest)
+		}
+		// the body can still be sent
+		if body, _ := io.ReadAll(req.Body); string(body) != `{"hello": "world"}` {
+			t.Errorf("expected body to be unchanged, got %q", body)
+		}
+	}
+
+	signer := &Signer{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"content-digest"}, DigestAlgorithm: "md5"}
+	if _, err := testRequest(signer).Request(); err == nil {
+		t.Errorf("expected unsupported digest algorithm error")
+	}
+}
+
+func TestSigner_errors(t *testing.T) {
+	cases := []*Signer{
+		{Algorithm: "rsa-v1_5-sha256", Key: testSharedSecret},
+		{Algorithm: Ed25519, Key: testSharedSecret},
+		{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"x-missing"}},
+		{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"@status"}},
+		{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"@method", "@method"}},
+		{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{`@query-param;name="missing"`}},
+		{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"@query-param;name=?"}},
+	}
+	for _, signer := range cases {
+		if _, err := testRequest(signer).Request(); err == nil {
+			t.Errorf("%+v: expected error, got nil", signer)
+		}
+	}
+}
+
+func TestSignatureBase(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://example.com/foo?param=Value&Pet=dog", nil)
+	items, _ := parseComponents([]string{"@method", "@target-uri", "@authority", "@scheme", "@request-target", "@path", "@query", `@query-param;name="Pet"`})
+	base, err := signatureBase(&message{req: req}, items, []sfParam{{name: "created", value: int64(1618884473)}})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	// RFC 9421 Section 2.2
+	expected := `"@method": POST
+"@target-uri": http://example.com/foo?param=Value&Pet=dog
+"@authority": example.com
+"@scheme": http
+"@request-target": /foo?param=Value&Pet=dog
+"@path": /foo
+"@query": ?param=Value&Pet=dog
+"@query-param";name="Pet": dog
+"@signature-params": ("@method" "@target-uri" "@authority" "@scheme" "@request-target" "@path" "@query" "@query-param";name="Pet");created=1618884473`
+	if base != expected {
+		t.Errorf("expected %s, got %s", expected, base)
+	}
+}
+
+func TestSignatureBase_queryParam(t *testing.T) {
+	// RFC 9421 Section 2.2.8
+	req, _ := http.NewRequest("GET", "https://example.com/parameters?var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace&fa%C3%A7ade%22%3A%20=something&qux=", nil)
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"var", "this%20is%20a%20big%0Avalue"},
+		{"bar", "with%20plus%20whitespace"},
+		{"fa%C3%A7ade%22%3A%20", "something"},
+		{"qux", ""},
+	}
+	for _, c := range cases {
+		value, err := componentValue(&message{req: req}, "@query-param", []sfParam{{name: "name", value: c.name}})
+		if err != nil {
+			t.Errorf("%s: expected nil, got %v", c.name, err)
+		}
+		if value != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, value)
+		}
+	}
+}
+
+func TestVerifier_roundTrip(t *testing.T) {
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	cases := []struct {
+		algorithm Algorithm
+		priv      interface{}
+		pub       interface{}
+	}{
+		{HMACSHA256, testSharedSecret, testSharedSecret},
+		{Ed25519, priv, pub},
+		{ECDSAP256SHA256, ecdsaKey, &ecdsaKey.PublicKey},
+		{RSAPSSSHA512, rsaKey, &rsaKey.PublicKey},
+	}
+	for _, c := range cases {
+		signer := &Signer{
+			Algorithm:  c.algorithm,
+			Key:        c.priv,
+			KeyID:      "key-1",
+			Components: []string{"@method", "@target-uri", "content-type", "content-digest"},
+			Expires:    time.Minute,
+		}
+		req, err := testRequest(signer).Request()
+		if err != nil {
+			t.Fatalf("%s: expected nil, got %v", c.algorithm, err)
+		}
+		verifier := &Verifier{Algorithm: c.algorithm, Key: c.pub, KeyID: "key-1", Components: []string{"@method", "content-digest"}, MaxAge: time.Minute}
+		if err := verifier.VerifyRequest(req); err != nil {
+			t.Errorf("%s: expected nil, got %v", c.algorithm, err)
+		}
+		if body, err := io.ReadAll(req.Body); err != nil || string(body) != `{"hello": "world"}` {
+			t.Errorf("%s: expected body, got %q %v", c.algorithm, body, err)
+		}
+
+		// tampered requests are rejected
+		req, _ = testRequest(signer).Request()
+		req.Header.Set("Content-Type", "text/plain")
+		if err := verifier.VerifyRequest(req); err != ErrInvalidSignature {
+			t.Errorf("%s: expected %v, got %v", c.algorithm, ErrInvalidSignature, err)
+		}
+	}
+}
+
+func TestVerifier_errors(t *testing.T) {
+	signer := &Signer{Algorithm: HMACSHA256, Key: testSharedSecret, KeyID: "key-1", Label: "a", Now: testCreated, Expires: time.Minute}
+	now := func() time.Time { return time.Unix(1618884473, 0).Add(30 * time.Second) }
+	cases := []struct {
+		name     string
+		verifier *Verifier
+	}{
+		{"label", &Verifier{Algorithm: HMACSHA256, Key: testSharedSecret, Label: "b", Now: now}},
+		{"keyid", &Verifier{Algorithm: HMACSHA256, Key: testSharedSecret, KeyID: "key-2", Now: now}},
+		{"key", &Verifier{Algorithm: HMACSHA256, Key: []byte("other"), Now: now}},
+		{"components", &Verifier{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"content-type"}, Now: now}},
+		{"expired", &Verifier{Algorithm: HMACSHA256, Key: testSharedSecret}},
+		{"max age", &Verifier{Algorithm: HMACSHA256, Key: testSharedSecret, MaxAge: time.Second, Now: now}},
+	}
+	for _, c := range cases {
+		req, _ := testRequest(signer).Request()
+		if err := c.verifier.VerifyRequest(req); err == nil {
+			t.Errorf("%s: expected error, got nil", c.name)
+		}
+	}
+	req, _ := testRequest(signer).Request()
+	verifier := &Verifier{Algorithm: HMACSHA256, Key: testSharedSecret, Components: []string{"@METHOD"}, MaxAge: time.Minute, Now: now}
+	if err := verifier.VerifyRequest(req); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+
+	req, _ = testRequest(nil).Request()
+	if err := verifier.VerifyRequest(req); err == nil {
+		t.Errorf("expected missing signature error")
+	}
+}
+
+func TestVerifier_VerifyResponse(t *testing.T) {
+	serverKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	body := `{"message": "good dog"}`
+	tampered := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "23")
+		// RFC 9421 Appendix B.2.4
+		w.Header().Set("Content-Digest", "sha-512=:mEWXIS7MaLRuGgxOBdODa3xqM1XdEvxoYhvlCFJ41QJgJc4GTsPp29l5oGX69wWdXymyU0rjJuahq4l5aGgfLQ==:")
+		resp := &http.Response{StatusCode: http.StatusOK, Header: w.Header(), Request: r}
+		items, _ := parseComponents([]string{"@status", "content-type", "content-digest", "@method;req"})
+		params := []sfParam{{name: "created", value: time.Now().Unix()}}
+		base, err := signatureBase(&message{req: r, resp: resp}, items, params)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		signature, _ := sign(ECDSAP256SHA256, serverKey, []byte(base))
+		w.Header().Set("Signature-Input", "sig="+serializeInnerList(items, params))
+		w.Header().Set("Signature", "sig="+serializeBareItem(signature))
+		if tampered {
+			body = `{"message": "bad dog!"}`
+		}
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+
+	verifier := &Verifier{Algorithm: ECDSAP256SHA256, Key: &serverKey.PublicKey, Components: []string{"@status", "content-digest"}}
+	client := sling.New().Base(server.URL).OnResponse(verifier.VerifyResponse)
+
+	data := make(map[string]string)
+	if _, err := client.New().Get("/").ReceiveSuccess(&data); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if data["message"] != "good dog" {
+		t.Errorf("expected good dog, got %v", data)
+	}
+
+	// the Body is verified as it's read, so response limits still apply
+	var tooLarge *sling.ResponseTooLargeError
+	if _, err := client.New().Get("/").MaxResponseBytes(10).ReceiveSuccess(&data); !errors.As(err, &tooLarge) {
+		t.Errorf("expected ResponseTooLargeError, got %v", err)
+	}
+
+	tampered = true
+	var mismatch *sling.DigestMismatchError
+	if _, err := client.New().Get("/").ReceiveSuccess(&data); !errors.As(err, &mismatch) || mismatch.Header != "Content-Digest" {
+		t.Errorf("expected DigestMismatchError, got %v", err)
+	}
+}
+
+func TestParseDictionary(t *testing.T) {
+	members, err := parseDictionary(`sig1=("@method" "@query-param";name="a\"b");created=-1;alg=ed25519, sig2=:AQI=:, flag, off=?0`)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(members) != 4 {
+		t.Fatalf("expected 4 members, got %d", len(members))
+	}
+	if len(members[0].list) != 2 || param(members[0].list[1].params, "name") != `a"b` {
+		t.Errorf("unexpected inner list %+v", members[0].list)
+	}
+	if param(members[0].params, "created") != int64(-1) || param(members[0].params, "alg") != sfToken("ed25519") {
+		t.Errorf("unexpected params %+v", members[0].params)
+	}
+	if b, _ := members[1].item.([]byte); string(b) != "\x01\x02" {
+		t.Errorf("expected byte sequence, got %v", members[1].item)
+	}
+	if members[2].item != true || members[3].item != false {
+		t.Errorf("expected booleans, got %v and %v", members[2].item, members[3].item)
+	}
+	if serialized := members[0].name + "=" + serializeInnerList(members[0].list, members[0].params); serialized != `sig1=("@method" "@query-param";name="a\"b");created=-1;alg=ed25519` {
+		t.Errorf("unexpected serialization %s", serialized)
+	}
+
+	for _, invalid := range []string{`sig=(`, `sig=("a"`, `sig="a`, `sig=:AQI`, `sig=?2`, `Sig=1`, `sig=1,`, `sig=1 2`} {
+		if _, err := parseDictionary(invalid); err == nil {
+			t.Errorf("%s: expected error, got nil", invalid)
+		}
+	}
+}
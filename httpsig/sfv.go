@@ -0,0 +1,271 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Structured Field Values (RFC 8941), limited to what Signature-Input and
+// Signature dictionaries use: strings, tokens, integers, booleans, byte
+// sequences, and inner lists with parameters.
+
+// sfParam is a parameter of an item or inner list.
+type sfParam struct {
+	name  string
+	value interface{}
+}
+
+// sfItem is a bare item with parameters.
+type sfItem struct {
+	value  interface{}
+	params []sfParam
+}
+
+// sfMember is a dictionary member, an item or an inner list.
+type sfMember struct {
+	name string
+	// items of an inner list, or nil for an item
+	list []sfItem
+	// item value, or nil for an inner list
+	item   interface{}
+	params []sfParam
+}
+
+// sfToken is a token bare item, which is serialized without quotes.
+type sfToken string
+
+// param returns the named parameter value, or nil.
+func param(params []sfParam, name string) interface{} {
+	for _, p := range params {
+		if p.name == name {
+			return p.value
+		}
+	}
+	return nil
+}
+
+// serializeParams serializes the parameters, in order.
+func serializeParams(params []sfParam) string {
+	var b strings.Builder
+	for _, p := range params {
+		b.WriteString(";" + p.name)
+		if v, ok := p.value.(bool); ok && v {
+			continue
+		}
+		b.WriteString("=" + serializeBareItem(p.value))
+	}
+	return b.String()
+}
+
+// serializeBareItem serializes a string, token, integer, boolean, or byte
+// sequence.
+func serializeBareItem(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	case sfToken:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		if v {
+			return "?1"
+		}
+		return "?0"
+	case []byte:
+		return ":" + base64.StdEncoding.EncodeToString(v) + ":"
+	}
+	return fmt.Sprint(v)
+}
+
+// serializeInnerList serializes the inner list and its parameters.
+func serializeInnerList(items []sfItem, params []sfParam) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = serializeBareItem(item.value) + serializeParams(item.params)
+	}
+	return "(" + strings.Join(parts, " ") + ")" + serializeParams(params)
+}
+
+// sfParser parses structured field values.
+type sfParser struct {
+	s string
+}
+
+// parseDictionary parses a dictionary, in order.
+func parseDictionary(s string) ([]sfMember, error) {
+	p := &sfParser{s: strings.TrimSpace(s)}
+	var members []sfMember
+	for p.s != "" {
+		name, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		member := sfMember{name: name, item: true}
+		if p.consume('=') {
+			if strings.HasPrefix(p.s, "(") {
+				member.item = nil
+				if member.list, err = p.innerList(); err != nil {
+					return nil, err
+				}
+			} else if member.item, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		if member.params, err = p.params(); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+		p.s = strings.TrimLeft(p.s, " \t")
+		if p.s == "" {
+			break
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("httpsig: invalid dictionary %q", s)
+		}
+		p.s = strings.TrimLeft(p.s, " \t")
+		if p.s == "" {
+			return nil, fmt.Errorf("httpsig: trailing comma in dictionary %q", s)
+		}
+	}
+	return members, nil
+}
+
+func (p *sfParser) consume(c byte) bool {
+	if strings.HasPrefix(p.s, string(c)) {
+		p.s = p.s[1:]
+		return true
+	}
+	return false
+}
+
+// key parses a dictionary or parameter key.
+func (p *sfParser) key() (string, error) {
+	i := 0
+	for ; i < len(p.s); i++ {
+		c := p.s[i]
+		if !('a' <= c && c <= 'z' || c == '*' || i > 0 && ('0' <= c && c <= '9' || c == '_' || c == '-' || c == '.')) {
+			break
+		}
+	}
+	if i == 0 {
+		return "", fmt.Errorf("httpsig: invalid key at %q", p.s)
+	}
+	key := p.s[:i]
+	p.s = p.s[i:]
+	return key, nil
+}
+
+// params parses parameters.
+func (p *sfParser) params() ([]sfParam, error) {
+	var params []sfParam
+	for p.consume(';') {
+		p.s = strings.TrimLeft(p.s, " ")
+		name, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{} = true
+		if p.consume('=') {
+			if value, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, sfParam{name: name, value: value})
+	}
+	return params, nil
+}
+
+// innerList parses an inner list of items.
+func (p *sfParser) innerList() ([]sfItem, error) {
+	p.consume('(')
+	var items []sfItem
+	for {
+		p.s = strings.TrimLeft(p.s, " ")
+		if p.consume(')') {
+			return items, nil
+		}
+		value, err := p.bareItem()
+		if err != nil {
+			return nil, err
+		}
+		params, err := p.params()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, sfItem{value: value, params: params})
+		if !strings.HasPrefix(p.s, " ") && !strings.HasPrefix(p.s, ")") {
+			return nil, fmt.Errorf("httpsig: invalid inner list at %q", p.s)
+		}
+	}
+}
+
+// bareItem parses a string, token, integer, boolean, or byte sequence.
+func (p *sfParser) bareItem() (interface{}, error) {
+	switch {
+	case p.s == "":
+		return nil, fmt.Errorf("httpsig: missing item")
+	case p.s[0] == '"':
+		var b strings.Builder
+		for i := 1; i < len(p.s); i++ {
+			switch c := p.s[i]; c {
+			case '\\':
+				if i+1 >= len(p.s) || p.s[i+1] != '"' && p.s[i+1] != '\\' {
+					return nil, fmt.Errorf("httpsig: invalid string escape")
+				}
+				i++
+				b.WriteByte(p.s[i])
+			case '"':
+				p.s = p.s[i+1:]
+				return b.String(), nil
+			default:
+				if c < 0x20 || c > 0x7e {
+					return nil, fmt.Errorf("httpsig: invalid string character")
+				}
+				b.WriteByte(c)
+			}
+		}
+		return nil, fmt.Errorf("httpsig: unterminated string")
+	case p.s[0] == ':':
+		end := strings.IndexByte(p.s[1:], ':')
+		if end < 0 {
+			return nil, fmt.Errorf("httpsig: unterminated byte sequence")
+		}
+		b, err := base64.StdEncoding.DecodeString(p.s[1 : end+1])
+		if err != nil {
+			return nil, fmt.Errorf("httpsig: invalid byte sequence: %w", err)
+		}
+		p.s = p.s[end+2:]
+		return b, nil
+	case p.s[0] == '?':
+		if len(p.s) < 2 || p.s[1] != '0' && p.s[1] != '1' {
+			return nil, fmt.Errorf("httpsig: invalid boolean")
+		}
+		v := p.s[1] == '1'
+		p.s = p.s[2:]
+		return v, nil
+	case p.s[0] == '-' || '0' <= p.s[0] && p.s[0] <= '9':
+		i := 1
+		for i < len(p.s) && '0' <= p.s[i] && p.s[i] <= '9' {
+			i++
+		}
+		n, err := strconv.ParseInt(p.s[:i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("httpsig: invalid integer: %w", err)
+		}
+		p.s = p.s[i:]
+		return n, nil
+	case 'a' <= p.s[0] && p.s[0] <= 'z' || 'A' <= p.s[0] && p.s[0] <= 'Z' || p.s[0] == '*':
+		i := 1
+		for i < len(p.s) && (strings.IndexByte("!#$%&'*+-.^_`|~:/", p.s[i]) >= 0 ||
+			'a' <= p.s[i] && p.s[i] <= 'z' || 'A' <= p.s[i] && p.s[i] <= 'Z' || '0' <= p.s[i] && p.s[i] <= '9') {
+			i++
+		}
+		token := sfToken(p.s[:i])
+		p.s = p.s[i:]
+		return token, nil
+	}
+	return nil, fmt.Errorf("httpsig: invalid item at %q", p.s)
+}
@@ -0,0 +1,134 @@
+package httpsig
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dghubble/sling"
+)
+
+// Verifier verifies HTTP Message Signatures of responses or requests.
+type Verifier struct {
+	// Algorithm is the signature algorithm of the Key.
+	Algorithm Algorithm
+	// Key is the verification key (see Algorithm).
+	Key interface{}
+	// KeyID, if set, must match the keyid parameter of the signature.
+	KeyID string
+	// Label selects the signature to verify. Defaults to the first signature.
+	Label string
+	// Components are component identifiers which must be covered by the
+	// signature (e.g. "@status", "content-digest").
+	Components []string
+	// MaxAge, if non-zero, rejects signatures created longer ago.
+	MaxAge time.Duration
+	// Now returns the verification time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// VerifyResponse verifies the signature of the response, which may cover
+// request components (e.g. "@method"). If the signature covers the
+// Content-Digest header, the Body is verified against it as it's read (see
+// sling.VerifyContentDigest), so reading the end of a mismatched Body returns
+// a *sling.DigestMismatchError. It can be used as a sling.ResponseHook (see
+// Sling.OnResponse), since Slings read verified Bodies to the end.
+func (v *Verifier) VerifyResponse(resp *http.Response) error {
+	m := &message{req: resp.Request, resp: resp}
+	covered, err := v.verify(m)
+	if err != nil || !covered {
+		return err
+	}
+	resp.Body, err = sling.VerifyContentDigest(resp.Header, resp.Body)
+	return err
+}
+
+// VerifyRequest verifies the signature of the request, such as one received
+// by a server. If the signature covers the Content-Digest header, the Body
+// is verified against it as it's read, so reading the end of a mismatched
+// Body returns a *sling.DigestMismatchError.
+func (v *Verifier) VerifyRequest(req *http.Request) error {
+	covered, err := v.verify(&message{req: req})
+	if err != nil || !covered {
+		return err
+	}
+	req.Body, err = sling.VerifyContentDigest(req.Header, req.Body)
+	return err
+}
+
+// verify verifies the message signature and returns whether it covers the
+// Content-Digest header.
+func (v *Verifier) verify(m *message) (bool, error) {
+	header := m.header()
+	inputs, err := parseDictionary(header.Get("Signature-Input"))
+	if err != nil {
+		return false, err
+	}
+	signatures, err := parseDictionary(header.Get("Signature"))
+	if err != nil {
+		return false, err
+	}
+	var input *sfMember
+	for i := range inputs {
+		if v.Label == "" || inputs[i].name == v.Label {
+			input = &inputs[i]
+			break
+		}
+	}
+	if input == nil || input.list == nil && input.item != nil {
+		return false, fmt.Errorf("httpsig: missing signature %q", v.Label)
+	}
+	var signature []byte
+	for _, member := range signatures {
+		if member.name == input.name {
+			signature, _ = member.item.([]byte)
+		}
+	}
+	if signature == nil {
+		return false, fmt.Errorf("httpsig: missing signature %q", input.name)
+	}
+
+	if keyID, _ := param(input.params, "keyid").(string); v.KeyID != "" && keyID != v.KeyID {
+		return false, fmt.Errorf("httpsig: unexpected keyid %q", keyID)
+	}
+	if alg, ok := param(input.params, "alg").(string); ok && Algorithm(alg) != v.Algorithm {
+		return false, fmt.Errorf("httpsig: unexpected alg %q", alg)
+	}
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if expires, ok := param(input.params, "expires").(int64); ok && now().Unix() > expires {
+		return false, fmt.Errorf("httpsig: signature expired")
+	}
+	if v.MaxAge != 0 {
+		created, ok := param(input.params, "created").(int64)
+		if !ok || now().Sub(time.Unix(created, 0)) > v.MaxAge {
+			return false, fmt.Errorf("httpsig: signature is missing created or too old")
+		}
+	}
+
+	required, err := parseComponents(v.Components)
+	if err != nil {
+		return false, err
+	}
+	identifiers := make([]string, len(input.list))
+	for i, item := range input.list {
+		identifiers[i] = serializeBareItem(item.value) + serializeParams(item.params)
+	}
+	for _, item := range required {
+		if identifier := serializeBareItem(item.value) + serializeParams(item.params); !slices.Contains(identifiers, identifier) {
+			return false, fmt.Errorf("httpsig: signature doesn't cover %s", identifier)
+		}
+	}
+
+	base, err := signatureBase(m, input.list, input.params)
+	if err != nil {
+		return false, err
+	}
+	if err := verify(v.Algorithm, v.Key, []byte(base), signature); err != nil {
+		return false, err
+	}
+	return slices.Contains(identifiers, `"content-digest"`), nil
+}
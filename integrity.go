@@ -0,0 +1,176 @@
+package sling
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Content-Digest algorithms (RFC 9530).
+const (
+	DigestSHA256 = "sha-256"
+	DigestSHA512 = "sha-512"
+)
+
+// digestHashes are the supported Content-Digest algorithms, strongest first.
+var digestHashes = []struct {
+	algorithm string
+	new       func() hash.Hash
+}{
+	{DigestSHA512, sha512.New},
+	{DigestSHA256, sha256.New},
+}
+
+// DigestMismatchError is returned when reading a response Body whose content
+// doesn't match its Content-Digest, Repr-Digest, or Content-MD5 header.
+type DigestMismatchError struct {
+	// Header is the digest header (e.g. "Content-Digest").
+	Header string
+	// Algorithm is the digest algorithm (e.g. "sha-256" or "md5").
+	Algorithm string
+	// Expected is the digest sent in the header.
+	Expected []byte
+	// Actual is the digest of the received content.
+	Actual []byte
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("sling: %s %s mismatch: expected %s, got %s", e.Header, e.Algorithm,
+		base64.StdEncoding.EncodeToString(e.Expected), base64.StdEncoding.EncodeToString(e.Actual))
+}
+
+// SetContentDigest sets the request Content-Digest header (RFC 9530) to the
+// digest of the Body with the DigestSHA256 or DigestSHA512 algorithm. The
+// Body is read with HashBody.
+func SetContentDigest(req *http.Request, algorithm string) error {
+	for _, h := range digestHashes {
+		if h.algorithm == algorithm {
+			hash := h.new()
+			if err := HashBody(req, hash); err != nil {
+				return err
+			}
+			req.Header.Set("Content-Digest", algorithm+"=:"+base64.StdEncoding.EncodeToString(hash.Sum(nil))+":")
+			return nil
+		}
+	}
+	return fmt.Errorf("sling: unsupported Content-Digest algorithm %q", algorithm)
+}
+
+// VerifyContentDigest returns a Body which verifies the content read from body
+// against the Content-Digest header (RFC 9530), using its strongest supported
+// algorithm, without buffering it. Reading the end of a Body which doesn't
+// match returns a *DigestMismatchError instead of io.EOF, so the Body should
+// be read to the end. Returns an error if the header has no supported digest.
+func VerifyContentDigest(header http.Header, body io.ReadCloser) (io.ReadCloser, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	verifier := newDigestVerifier("Content-Digest", header.Values("Content-Digest"))
+	if verifier == nil {
+		return body, fmt.Errorf("sling: no supported Content-Digest algorithm")
+	}
+	verifier.rc = body
+	return verifier, nil
+}
+
+// verifyDigest wraps the response Body to verify its digest as it is read.
+// Content-Digest and Content-MD5 describe the content as sent, so they're
+// only verified if the Transport hasn't decompressed it. Repr-Digest
+// describes the decoded representation, so it's only verified if the content
+// isn't encoded (or the Transport decoded it) and the response is complete.
+func verifyDigest(req *http.Request, resp *http.Response) {
+	if req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return
+	}
+	decoded := resp.Uncompressed || resp.Header.Get("Content-Encoding") == ""
+	var verifier *digestReadCloser
+	switch {
+	case !resp.Uncompressed && resp.Header.Get("Content-Digest") != "":
+		verifier = newDigestVerifier("Content-Digest", resp.Header.Values("Content-Digest"))
+	case decoded && resp.StatusCode != http.StatusPartialContent && resp.Header.Get("Repr-Digest") != "":
+		verifier = newDigestVerifier("Repr-Digest", resp.Header.Values("Repr-Digest"))
+	case !resp.Uncompressed && resp.Header.Get("Content-MD5") != "":
+		expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(resp.Header.Get("Content-MD5")))
+		if err == nil {
+			verifier = &digestReadCloser{header: "Content-MD5", algorithm: "md5", hash: md5.New(), expected: expected}
+		}
+	}
+	if verifier != nil {
+		verifier.rc = resp.Body
+		resp.Body = verifier
+	}
+}
+
+// verifyReprDigest wraps the Body of a response decompressed by the Sling to
+// verify its Repr-Digest as it is read.
+func verifyReprDigest(resp *http.Response) {
+	if resp.StatusCode == http.StatusPartialContent || resp.Header.Get("Repr-Digest") == "" {
+		return
+	}
+	if verifier := newDigestVerifier("Repr-Digest", resp.Header.Values("Repr-Digest")); verifier != nil {
+		verifier.rc = resp.Body
+		resp.Body = verifier
+	}
+}
+
+// newDigestVerifier returns a digestReadCloser for the strongest supported
+// algorithm in the Content-Digest or Repr-Digest dictionary (RFC 9530), or
+// nil if none are supported.
+func newDigestVerifier(header string, values []string) *digestReadCloser {
+	digests := make(map[string][]byte)
+	for _, member := range strings.Split(strings.Join(values, ","), ",") {
+		algorithm, value, _ := strings.Cut(strings.TrimSpace(member), "=")
+		// ignore any parameters
+		value, _, _ = strings.Cut(value, ";")
+		value = strings.TrimSpace(value)
+		if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			continue
+		}
+		if digest, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1]); err == nil {
+			digests[strings.ToLower(algorithm)] = digest
+		}
+	}
+	for _, h := range digestHashes {
+		if expected, ok := digests[h.algorithm]; ok {
+			return &digestReadCloser{header: header, algorithm: h.algorithm, hash: h.new(), expected: expected}
+		}
+	}
+	return nil
+}
+
+// digestReadCloser hashes the content read from a response Body and returns
+// a *DigestMismatchError instead of io.EOF if the digest doesn't match.
+type digestReadCloser struct {
+	rc        io.ReadCloser
+	header    string
+	algorithm string
+	hash      hash.Hash
+	expected  []byte
+}
+
+func (r *digestReadCloser) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && n > 0 {
+		// return the mismatch from the next Read, without content, since
+		// some readers ignore errors returned with content
+		return n, nil
+	}
+	if err == io.EOF {
+		if actual := r.hash.Sum(nil); subtle.ConstantTimeCompare(actual, r.expected) != 1 {
+			return n, &DigestMismatchError{Header: r.header, Algorithm: r.algorithm, Expected: r.expected, Actual: actual}
+		}
+	}
+	return n, err
+}
+
+func (r *digestReadCloser) Close() error {
+	return r.rc.Close()
+}
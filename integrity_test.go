@@ -0,0 +1,245 @@
+package sling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestContentDigest(t *testing.T) {
+	cases := []struct {
+		algorithm string
+		expected  string
+	}{
+		// RFC 9530 Appendix B
+		{DigestSHA256, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:"},
+		{DigestSHA512, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"},
+	}
+	for _, c := range cases {
+		req, err := New().Post("http://example.com/").Body(strings.NewReader(`{"hello": "world"}`)).ContentDigest(c.algorithm).Request()
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if digest := req.Header.Get("Content-Digest"); digest != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, digest)
+		}
+	}
+
+	// Bodies from BodyProviders are digested, and signers see the digest
+	var signed string
+	req, err := New().Post("http://example.com/").BodyJSON(map[string]string{"hello": "world"}).ContentDigest(DigestSHA256).
+		Signer(SignerFunc(func(req *http.Request) error {
+			signed = req.Header.Get("Content-Digest")
+			return nil
+		})).Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	sum := sha256.Sum256([]byte("{\"hello\":\"world\"}\n"))
+	if expected := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"; signed != expected {
+		t.Errorf("expected %s, got %s", expected, signed)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "{\"hello\":\"world\"}\n" {
+		t.Errorf("expected body to be unchanged, got %q", body)
+	}
+
+	// requests without Bodies aren't digested
+	if req, _ := New().Get("http://example.com/").ContentDigest(DigestSHA256).Request(); req.Header.Get("Content-Digest") != "" {
+		t.Errorf("expected no Content-Digest, got %v", req.Header)
+	}
+	if _, err := New().Post("http://example.com/").BodyJSON(modelA).ContentDigest("md5").Request(); err == nil {
+		t.Errorf("expected unsupported algorithm error, got nil")
+	}
+}
+
+// digestHeader returns a RFC 9530 digest header value of the content.
+func digestHeader(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+func TestVerifyDigest(t *testing.T) {
+	const body = `{"text": "Some text"}` + "\n\n"
+	md5Sum := md5.Sum([]byte(body))
+	cases := []struct {
+		header   string
+		value    string
+		mismatch bool
+	}{
+		{"Content-Digest", digestHeader(body), false},
+		{"Content-Digest", digestHeader("other"), true},
+		// the strongest algorithm is verified, and unsupported ones ignored
+		{"Content-Digest", "sha-512=:" + base64.StdEncoding.EncodeToString(make([]byte, 64)) + ":, " + digestHeader(body), true},
+		{"Content-Digest", "unixsum=:AAAA:, " + digestHeader(body), false},
+		{"Repr-Digest", digestHeader(body), false},
+		{"Repr-Digest", digestHeader("other"), true},
+		{"Content-MD5", base64.StdEncoding.EncodeToString(md5Sum[:]), false},
+		{"Content-MD5", base64.StdEncoding.EncodeToString(make([]byte, 16)), true},
+		// digests of unsupported algorithms aren't verified
+		{"Content-Digest", "unixsum=:AAAA:", false},
+	}
+	for _, c := range cases {
+		client, mux, server := testServer()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(c.header, c.value)
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, body)
+		})
+		model := new(FakeModel)
+		_, err := New().Client(client).Get("http://example.com/").VerifyDigest().ReceiveSuccess(model)
+		// the digest is verified after decoding, even if the decoder didn't
+		// read to the end of the Body
+		var mismatch *DigestMismatchError
+		if c.mismatch {
+			if !errors.As(err, &mismatch) || mismatch.Header != c.header {
+				t.Errorf("%s %s: expected DigestMismatchError, got %v", c.header, c.value, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s %s: expected nil, got %v", c.header, c.value, err)
+		}
+		if model.Text != "Some text" {
+			t.Errorf("expected decoded value, got %v", model)
+		}
+		server.Close()
+	}
+}
+
+func TestVerifyDigest_stream(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Digest", digestHeader("expected"))
+		io.WriteString(w, r.URL.Query().Get("body"))
+	})
+	base := New().Client(client).Base("http://example.com/").VerifyDigest()
+
+	for _, body := range []string{"expected", "unexpected"} {
+		resp, err := base.New().QueryParam("body", body).ReceiveStream(nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		content, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		var mismatch *DigestMismatchError
+		if body == "expected" && err != nil || body == "unexpected" && !errors.As(err, &mismatch) {
+			t.Errorf("%s: unexpected error %v", body, err)
+		}
+		if string(content) != body {
+			t.Errorf("expected %q, got %q", body, content)
+		}
+	}
+}
+
+func TestVerifyDigest_array(t *testing.T) {
+	const body = `[{"text": "a"}, {"text": "b"}]` + "\n"
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Digest", digestHeader(r.URL.Query().Get("digest")))
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	})
+	base := New().Client(client).Base("http://example.com/").VerifyDigest()
+
+	// elements are decoded as they're read, so mismatches are reported last
+	for _, digest := range []string{body, "other"} {
+		var texts []string
+		var err error
+		for model, modelErr := range ReceiveArray[FakeModel](base.New().QueryParam("digest", digest), "", nil) {
+			if modelErr != nil {
+				err = modelErr
+				break
+			}
+			texts = append(texts, model.Text)
+		}
+		var mismatch *DigestMismatchError
+		if digest == body && err != nil || digest == "other" && !errors.As(err, &mismatch) {
+			t.Errorf("%q: unexpected error %v", digest, err)
+		}
+		if len(texts) != 2 {
+			t.Errorf("expected 2 elements, got %v", texts)
+		}
+
+		_, err = ReceiveArrayFunc(base.New().QueryParam("digest", digest), "", nil, func(FakeModel) error { return nil })
+		if digest == body && err != nil || digest == "other" && !errors.As(err, &mismatch) {
+			t.Errorf("%q: unexpected error %v", digest, err)
+		}
+	}
+}
+
+func TestVerifyDigest_compressed(t *testing.T) {
+	const body = "hello gzip"
+	gzipped := new(bytes.Buffer)
+	zw := gzip.NewWriter(gzipped)
+	io.WriteString(zw, body)
+	zw.Close()
+
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Content-Digest is of the encoded content, Repr-Digest of the
+		// decoded representation
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Digest", digestHeader(gzipped.String()))
+		w.Header().Set("Repr-Digest", r.URL.Query().Get("repr"))
+		w.Write(gzipped.Bytes())
+	})
+	base := New().Client(client).Base("http://example.com/").VerifyDigest()
+
+	// the Transport decodes the content, so Repr-Digest is verified
+	resp, err := base.New().QueryParam("repr", digestHeader(body)).ReceiveStream(nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	content, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(content) != body {
+		t.Errorf("expected %q, got %q %v", body, content, err)
+	}
+	_, err = base.New().QueryParam("repr", digestHeader("other")).ReceiveSuccess(nil)
+	var mismatch *DigestMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Header != "Repr-Digest" {
+		t.Errorf("expected Repr-Digest mismatch, got %v", err)
+	}
+
+	// with an explicit Accept-Encoding, Content-Digest of the encoded content
+	// is verified before it's decompressed
+	resp, err = base.New().Set("Accept-Encoding", "gzip").ReceiveStream(nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	content, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(content) != body {
+		t.Errorf("expected %q, got %q %v", body, content, err)
+	}
+}
+
+func TestDigestMismatchError(t *testing.T) {
+	err := &DigestMismatchError{Header: "Content-Digest", Algorithm: DigestSHA256, Expected: []byte{1}, Actual: []byte{2}}
+	expected := "sling: Content-Digest sha-256 mismatch: expected AQ==, got Ag=="
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestVerifyDigest_head(t *testing.T) {
+	// HEAD responses aren't verified, since they have no content
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Digest", digestHeader("content"))
+		fmt.Fprint(w, "content")
+	})
+	if _, err := New().Client(client).Head("http://example.com/").VerifyDigest().ReceiveSuccess(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package sling
+
+import (
+	"fmt"
+	"io"
+)
+
+// ResponseTooLargeError is returned when reading a response Body larger than
+// the Sling's MaxResponseBytes.
+type ResponseTooLargeError struct {
+	// Limit is the maximum number of response Body bytes.
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("sling: response Body exceeds %d bytes", e.Limit)
+}
+
+// limitedReadCloser reads up to limit bytes from a Body and returns a
+// *ResponseTooLargeError if the Body has more.
+type limitedReadCloser struct {
+	rc    io.ReadCloser
+	limit int64
+	n     int64
+	// error returned by every Read once the limit is exceeded
+	err error
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.n >= r.limit {
+		// check whether the Body has more content
+		var b [1]byte
+		n, err := r.rc.Read(b[:])
+		if n > 0 {
+			r.err = &ResponseTooLargeError{Limit: r.limit}
+			return 0, r.err
+		}
+		return 0, err
+	}
+	if remaining := r.limit - r.n; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.rc.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *limitedReadCloser) Close() error {
+	return r.rc.Close()
+}
+
+// drain reads the remainder of a response Body, up to the Sling's
+// MaxDrainBytes, so the Transport can reuse the connection once the Body is
+// closed. Bodies with more content are closed without being read further,
+// which closes the connection.
+func (s *Sling) drain(body io.Reader) {
+	if s.maxDrainBytes < 0 {
+		io.Copy(io.Discard, body)
+		return
+	}
+	io.CopyN(io.Discard, body, s.maxDrainBytes)
+}
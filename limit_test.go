@@ -0,0 +1,151 @@
+package sling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+)
+
+func TestMaxResponseBytes(t *testing.T) {
+	const body = `{"text": "Some text"}`
+	cases := []struct {
+		limit   int64
+		chunked bool
+		tooBig  bool
+	}{
+		{0, false, false},
+		{int64(len(body)), false, false},
+		{int64(len(body)), true, false},
+		{10, false, true},
+		// Bodies of unknown length are limited as they're read
+		{10, true, true},
+	}
+	for _, c := range cases {
+		client, mux, server := testServer()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if c.chunked {
+				w.(http.Flusher).Flush()
+			}
+			io.WriteString(w, body)
+		})
+		model := new(FakeModel)
+		_, err := New().Client(client).Get("http://example.com/").MaxResponseBytes(c.limit).ReceiveSuccess(model)
+		var tooLarge *ResponseTooLargeError
+		if c.tooBig {
+			if !errors.As(err, &tooLarge) || tooLarge.Limit != c.limit {
+				t.Errorf("%v: expected ResponseTooLargeError, got %v", c, err)
+			}
+		} else if err != nil || model.Text != "Some text" {
+			t.Errorf("%v: expected decoded model, got %v %v", c, model, err)
+		}
+		server.Close()
+	}
+}
+
+func TestMaxResponseBytes_failure(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"message": %q}`, strings.Repeat("invalid ", 100))
+	})
+	base := New().Client(client).Get("http://example.com/").MaxResponseBytes(64)
+
+	apiError := new(APIError)
+	_, err := base.New().Receive(nil, apiError)
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("expected ResponseTooLargeError, got %v", err)
+	}
+
+	// HTTPErrors keep the failure response and report the limit, without a
+	// decoded failure value
+	_, err = base.New().ErrorOnFailure().Receive(nil, apiError)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Failure != nil || len(httpErr.Body) != 64 {
+		t.Errorf("expected HTTPError without Failure, got %v", err)
+	}
+	if !errors.As(err, &tooLarge) || !errors.As(httpErr.DecodeErr, &tooLarge) {
+		t.Errorf("expected ResponseTooLargeError, got %v", err)
+	}
+}
+
+func TestMaxResponseBytes_stream(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		io.WriteString(w, strings.Repeat("a", 100))
+	})
+	resp, err := New().Client(client).Get("http://example.com/").MaxResponseBytes(64).ReceiveStream(nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || len(content) != 64 {
+		t.Errorf("expected 64 bytes and ResponseTooLargeError, got %d %v", len(content), err)
+	}
+}
+
+func TestMaxResponseBytes_decompressed(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	gzipped := compress(t, EncodingGzip, `{"text": "`+strings.Repeat("a", 1000)+`"}`)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", EncodingGzip)
+		w.Write(gzipped)
+	})
+	// the limit applies to the decompressed Body
+	_, err := New().Client(client).Get("http://example.com/").Set("Accept-Encoding", "gzip").MaxResponseBytes(512).ReceiveSuccess(new(FakeModel))
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("expected ResponseTooLargeError, got %v", err)
+	}
+}
+
+func TestMaxDrainBytes(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// a value followed by more content than decoders read
+		io.WriteString(w, `{"text": "Some text"}`+strings.Repeat(" ", 1<<20))
+	})
+
+	cases := []struct {
+		sling  *Sling
+		reused bool
+	}{
+		// the remainder is drained by default, so connections are reused
+		{New(), true},
+		{New().MaxDrainBytes(2 << 20), true},
+		{New().MaxDrainBytes(1024), false},
+	}
+	for _, c := range cases {
+		var reused []bool
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				reused = append(reused, info.Reused)
+			},
+		}
+		base := c.sling.Client(client).Get("http://example.com/").Context(httptrace.WithClientTrace(context.Background(), trace))
+		for i := 0; i < 2; i++ {
+			if _, err := base.New().ReceiveSuccess(new(FakeModel)); err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+		}
+		if len(reused) != 2 || reused[1] != c.reused {
+			t.Errorf("expected second connection reused %t, got %v", c.reused, reused)
+		}
+		client.CloseIdleConnections()
+	}
+}
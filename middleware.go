@@ -0,0 +1,108 @@
+package sling
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// DoerFunc is an adapter to allow the use of ordinary functions as Doers.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer with client-side behavior, such as setting request
+// headers or logging responses. Middleware should not modify the request it
+// is given, but may pass a clone to the next Doer.
+type Middleware func(next Doer) Doer
+
+// Chain wraps the Doer with the middleware. The first middleware is the
+// outermost, so it sees requests first and responses last.
+func Chain(doer Doer, middleware ...Middleware) Doer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		doer = middleware[i](doer)
+	}
+	return doer
+}
+
+// RequestHook is called with each request created by a Sling. Returning an
+// error aborts the request.
+type RequestHook func(req *http.Request) error
+
+// ResponseHook is called with each response received by a Sling, before it
+// is decoded. Returning an error closes the response Body and returns the
+// error.
+type ResponseHook func(resp *http.Response) error
+
+// Headers returns Middleware which sets the headers on each request,
+// replacing existing values.
+func Headers(header http.Header) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for key, values := range header {
+				req.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// RequestID returns Middleware which sets the header (e.g. "X-Request-Id")
+// on requests which don't have it to an ID returned by newID. If newID is
+// nil, random 128-bit hex IDs are used.
+func RequestID(header string, newID func() string) Middleware {
+	if newID == nil {
+		newID = randomID
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				req = req.Clone(req.Context())
+				req.Header.Set(header, newID())
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// randomID returns a random 128-bit hex ID.
+func randomID() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// Logger returns Middleware which logs the method, URL (with any password
+// redacted), status code, and duration of each request to the logger.
+// Headers and bodies, which may hold credentials, are not logged. If logger
+// is nil, slog.Default() is used.
+func Logger(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(req.Context(), slog.LevelError, "sling: request failed", attrs...)
+				return resp, err
+			}
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			logger.LogAttrs(req.Context(), slog.LevelInfo, "sling: request", attrs...)
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package sling
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	goquery "github.com/google/go-querystring/query"
+)
+
+const octetStreamContentType = "application/octet-stream"
+
+// Part is a part of a multipart/form-data request Body.
+type Part interface {
+	// writePart writes the part to the multipart writer.
+	writePart(w *multipart.Writer) error
+}
+
+// fieldPart is a form field part.
+type fieldPart struct {
+	name  string
+	value string
+}
+
+// FormField returns a Part for a form field with the given name and value.
+func FormField(name, value string) Part {
+	return fieldPart{name: name, value: value}
+}
+
+func (p fieldPart) writePart(w *multipart.Writer) error {
+	return w.WriteField(p.name, p.value)
+}
+
+// structPart encodes a url tagged struct as form field parts.
+type structPart struct {
+	payload interface{}
+}
+
+// FormStruct returns a Part which encodes the url tagged struct as form
+// fields. See https://godoc.org/github.com/google/go-querystring/query for
+// details.
+func FormStruct(v interface{}) Part {
+	return structPart{payload: v}
+}
+
+func (p structPart) writePart(w *multipart.Writer) error {
+	values, err := goquery.Values(p.payload)
+	if err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		for _, value := range values[key] {
+			if err := w.WriteField(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// filePart is a file part read from an io.Reader or a file path.
+type filePart struct {
+	name        string
+	filename    string
+	contentType string
+	reader      io.Reader
+	path        string
+}
+
+// FileReader returns a Part for a file with the given form field name,
+// filename, and Content-Type (defaults to "application/octet-stream"), whose
+// content is streamed from the reader. The reader can only be read once, so
+// requests with the Part aren't retried or sent again for redirects.
+func FileReader(name, filename, contentType string, r io.Reader) Part {
+	return filePart{name: name, filename: filename, contentType: contentType, reader: r}
+}
+
+// FilePath returns a Part for the file at the path with the given form field
+// name and Content-Type (defaults to "application/octet-stream"). The file is
+// opened and streamed each time a request Body is created.
+func FilePath(name, path, contentType string) Part {
+	return filePart{name: name, filename: filepath.Base(path), contentType: contentType, path: path}
+}
+
+func (p filePart) writePart(w *multipart.Writer) error {
+	r := p.reader
+	if p.path != "" {
+		file, err := os.Open(p.path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	ct := p.contentType
+	if ct == "" {
+		ct = octetStreamContentType
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(p.name), escapeQuotes(p.filename)))
+	header.Set(contentType, ct)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, r)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes escapes quotes in Content-Disposition parameters, as done by
+// mime/multipart.
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// multipartBodyProvider streams parts as a multipart/form-data Body for
+// requests.
+type multipartBodyProvider struct {
+	parts    []Part
+	boundary string
+}
+
+// newMultipartBodyProvider returns a multipartBodyProvider with a random
+// boundary.
+func newMultipartBodyProvider(parts []Part) multipartBodyProvider {
+	var buf [30]byte
+	if _, err := io.ReadFull(rand.Reader, buf[:]); err != nil {
+		panic(err)
+	}
+	return multipartBodyProvider{parts: parts, boundary: fmt.Sprintf("%x", buf[:])}
+}
+
+func (p multipartBodyProvider) ContentType() string {
+	return "multipart/form-data; boundary=" + p.boundary
+}
+
+// replayable returns false if a part is read from a one-shot reader.
+func (p multipartBodyProvider) replayable() bool {
+	for _, part := range p.parts {
+		if file, ok := part.(filePart); ok && file.path == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (p multipartBodyProvider) Body() (io.Reader, error) {
+	return p.BodyContext(context.Background())
+}
+
+// BodyContext returns a reader which streams the encoded parts through an
+// io.Pipe, so large files aren't buffered in memory. Encoding stops if the
+// context is done or the reader is closed.
+func (p multipartBodyProvider) BodyContext(ctx context.Context) (io.Reader, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		w := multipart.NewWriter(pw)
+		w.SetBoundary(p.boundary)
+		stop := context.AfterFunc(ctx, func() {
+			pw.CloseWithError(ctx.Err())
+		})
+		defer stop()
+		for _, part := range p.parts {
+			if err := part.writePart(w); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(w.Close())
+	}()
+	return pr, nil
+}
@@ -0,0 +1,153 @@
+package sling
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBodyMultipart(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("file contents"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		assertMethod(t, "POST", r)
+		if r.ContentLength != -1 {
+			t.Errorf("expected a streamed Body with unknown length, got %d", r.ContentLength)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		expectedValues := map[string]string{"kind_name": "recent", "count": "25", "title": "gophers"}
+		for key, expected := range expectedValues {
+			if value := r.FormValue(key); value != expected {
+				t.Errorf("%s: expected %s, got %s", key, expected, value)
+			}
+		}
+
+		cases := []struct {
+			field       string
+			filename    string
+			contentType string
+			content     string
+		}{
+			{"image", "gopher.png", "image/png", "png bytes"},
+			{"notes", "notes.txt", "text/plain", "file contents"},
+			{"raw", "raw.bin", octetStreamContentType, "raw bytes"},
+		}
+		for _, c := range cases {
+			file, header, err := r.FormFile(c.field)
+			if err != nil {
+				t.Fatalf("%s: expected nil, got %v", c.field, err)
+			}
+			content, _ := io.ReadAll(file)
+			file.Close()
+			if header.Filename != c.filename {
+				t.Errorf("expected %s, got %s", c.filename, header.Filename)
+			}
+			if ct := header.Header.Get("Content-Type"); ct != c.contentType {
+				t.Errorf("expected %s, got %s", c.contentType, ct)
+			}
+			if string(content) != c.content {
+				t.Errorf("expected %s, got %s", c.content, content)
+			}
+		}
+		w.WriteHeader(204)
+	})
+
+	s := New().Client(client).Post("http://example.com/upload").BodyMultipart(
+		FormStruct(paramsB),
+		FormField("title", "gophers"),
+		FileReader("image", "gopher.png", "image/png", strings.NewReader("png bytes")),
+		FilePath("notes", path, "text/plain"),
+		FileReader("raw", "raw.bin", "", strings.NewReader("raw bytes")),
+	)
+	if ct := s.header.Get(contentType); !strings.HasPrefix(ct, "multipart/form-data; boundary=") {
+		t.Errorf("expected multipart/form-data Content-Type with boundary, got %s", ct)
+	}
+	resp, err := s.Receive(nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if resp.StatusCode != 204 {
+		t.Errorf("expected %d, got %d", 204, resp.StatusCode)
+	}
+}
+
+func TestBodyMultipart_redirect(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("file contents"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+			return
+		}
+		defer file.Close()
+		if content, _ := io.ReadAll(file); string(content) != "file contents" {
+			t.Errorf("expected %s, got %s", "file contents", content)
+		}
+		w.WriteHeader(204)
+	})
+
+	cases := []struct {
+		part     Part
+		expected int
+	}{
+		// files are opened again for the redirected request
+		{FilePath("file", path, ""), 204},
+		// one-shot readers can't be sent again, so the redirect is returned
+		{FileReader("file", "notes.txt", "", strings.NewReader("file contents")), 307},
+	}
+	for _, c := range cases {
+		resp, err := New().Client(client).Post("http://example.com/upload").BodyMultipart(FormField("title", "gophers"), c.part).Receive(nil, nil)
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if resp.StatusCode != c.expected {
+			t.Errorf("expected %d, got %d", c.expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestBodyMultipart_errors(t *testing.T) {
+	s := New().Post("http://example.com/upload").BodyMultipart(FilePath("file", filepath.Join(t.TempDir(), "missing"), ""))
+	req, err := s.Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, err := io.ReadAll(req.Body); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %v, got %v", os.ErrNotExist, err)
+	}
+
+	// encoding stops when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked, unblock := io.Pipe()
+	defer unblock.Close()
+	req, err = New().Post("http://example.com/upload").BodyMultipart(FileReader("file", "f", "", blocked)).RequestContext(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	cancel()
+	if _, err := io.ReadAll(req.Body); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
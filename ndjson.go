@@ -0,0 +1,132 @@
+package sling
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonBodyProvider streams newline-delimited JSON values as a Body for
+// requests.
+type ndjsonBodyProvider struct {
+	// encode encodes each value with the encoder
+	encode func(ctx context.Context, enc *json.Encoder) error
+	// oneShot is set if values can only be encoded once
+	oneShot bool
+}
+
+// NDJSONSeq returns a BodyProvider which streams the items of the sequence as
+// newline-delimited JSON (application/x-ndjson). The sequence is iterated
+// each time a request Body is created, so it should be re-iterable if
+// requests may be retried.
+func NDJSONSeq[T any](seq iter.Seq[T]) BodyProvider {
+	return ndjsonBodyProvider{encode: func(ctx context.Context, enc *json.Encoder) error {
+		for item := range seq {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// NDJSONChan returns a BodyProvider which streams items received from the
+// channel as newline-delimited JSON (application/x-ndjson), until the channel
+// is closed. The channel can only be received from once, so requests with
+// the BodyProvider aren't retried or sent again for redirects.
+func NDJSONChan[T any](ch <-chan T) BodyProvider {
+	return ndjsonBodyProvider{encode: func(ctx context.Context, enc *json.Encoder) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case item, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				if err := enc.Encode(item); err != nil {
+					return err
+				}
+			}
+		}
+	}, oneShot: true}
+}
+
+func (p ndjsonBodyProvider) ContentType() string {
+	return ndjsonContentType
+}
+
+func (p ndjsonBodyProvider) replayable() bool {
+	return !p.oneShot
+}
+
+func (p ndjsonBodyProvider) Body() (io.Reader, error) {
+	return p.BodyContext(context.Background())
+}
+
+// BodyContext returns a reader which streams encoded values through an
+// io.Pipe, so values aren't buffered in memory. Encoding stops if the context
+// is done or the reader is closed.
+func (p ndjsonBodyProvider) BodyContext(ctx context.Context) (io.Reader, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		stop := context.AfterFunc(ctx, func() {
+			pw.CloseWithError(ctx.Err())
+		})
+		defer stop()
+		pw.CloseWithError(p.encode(ctx, json.NewEncoder(pw)))
+	}()
+	return pr, nil
+}
+
+// ReceiveNDJSON returns an iterator which sends the Sling's request and
+// decodes each newline-delimited JSON record of a success (2XX) response into
+// a value of type T, without buffering the response. Non-2XX responses are
+// decoded into the value pointed to by failureV (if non-nil) and yielded as
+// an *HTTPError. Errors sending the request or decoding records are yielded
+// last.
+func ReceiveNDJSON[T any](s *Sling, failureV interface{}) iter.Seq2[T, error] {
+	return receiveSeq(ndjsonSling(s), failureV, decodeNDJSON[T])
+}
+
+// ReceiveNDJSONFunc sends the Sling's request and calls fn with each
+// newline-delimited JSON record of a success (2XX) response decoded into a
+// value of type T, stopping if fn returns an error. Non-2XX responses are
+// decoded into the value pointed to by failureV (if non-nil) and return an
+// *HTTPError. Returns the error from fn or any error sending the request or
+// decoding records.
+func ReceiveNDJSONFunc[T any](s *Sling, failureV interface{}, fn func(item T) error) (*http.Response, error) {
+	return receiveFunc(ndjsonSling(s), failureV, fn, decodeNDJSON[T])
+}
+
+// ndjsonSling returns a child Sling which accepts newline-delimited JSON,
+// unless an Accept header is set.
+func ndjsonSling(s *Sling) *Sling {
+	s = s.New()
+	if s.header.Get("Accept") == "" {
+		s = s.Set("Accept", ndjsonContentType)
+	}
+	return s
+}
+
+// decodeNDJSON decodes newline-delimited JSON records from the response Body,
+// calling yield with each until yield returns false.
+func decodeNDJSON[T any](resp *http.Response, yield func(T) bool) error {
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !yield(item) {
+			return nil
+		}
+	}
+}
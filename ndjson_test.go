@@ -0,0 +1,152 @@
+package sling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestNDJSONBodyProviders(t *testing.T) {
+	models := []FakeModel{{Text: "a", FavoriteCount: 1}, {Text: "b"}}
+	expected := "{\"text\":\"a\",\"favorite_count\":1}\n{\"text\":\"b\"}\n"
+
+	ch := make(chan FakeModel)
+	go func() {
+		defer close(ch)
+		for _, model := range models {
+			ch <- model
+		}
+	}()
+	providers := map[string]BodyProvider{
+		"seq":  NDJSONSeq(slices.Values(models)),
+		"chan": NDJSONChan(ch),
+	}
+	for name, provider := range providers {
+		req, err := New().Post("http://example.com/import").BodyProvider(provider).Request()
+		if err != nil {
+			t.Fatalf("%s: expected nil, got %v", name, err)
+		}
+		if ct := req.Header.Get(contentType); ct != ndjsonContentType {
+			t.Errorf("%s: expected %s, got %s", name, ndjsonContentType, ct)
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil || string(body) != expected {
+			t.Errorf("%s: expected %q, got %q %v", name, expected, body, err)
+		}
+		// channels can only be received from once, so Bodies can't be
+		// re-obtained for retries and redirects
+		if replayable := name == "seq"; (req.GetBody != nil) != replayable {
+			t.Errorf("%s: expected GetBody set %t", name, replayable)
+		}
+	}
+}
+
+func TestNDJSONChan_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	body, err := NDJSONChan(make(chan int)).(ContextBodyProvider).BodyContext(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	cancel()
+	if _, err := io.ReadAll(body); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestReceiveNDJSON(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != ndjsonContentType {
+			t.Errorf("expected %s, got %s", ndjsonContentType, accept)
+		}
+		w.Header().Set("Content-Type", ndjsonContentType)
+		fmt.Fprint(w, "{\"text\": \"a\"}\n{\"text\": \"b\"}\n\n{\"text\": \"c\"}\n")
+	})
+	mux.HandleFunc("/invalid", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{\"text\": \"a\"}\n{\"text\": \n")
+	})
+	mux.HandleFunc("/failure", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		fmt.Fprint(w, `{"message": "Invalid argument", "code": 215}`)
+	})
+
+	base := New().Client(client).Base("http://example.com/")
+	var models []FakeModel
+	for model, err := range ReceiveNDJSON[FakeModel](base.New().Get("export"), nil) {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		models = append(models, model)
+	}
+	expected := []FakeModel{{Text: "a"}, {Text: "b"}, {Text: "c"}}
+	if !reflect.DeepEqual(expected, models) {
+		t.Errorf("expected %v, got %v", expected, models)
+	}
+
+	// stop early
+	models = nil
+	for model := range ReceiveNDJSON[FakeModel](base.New().Get("export"), nil) {
+		models = append(models, model)
+		break
+	}
+	if len(models) != 1 {
+		t.Errorf("expected %d models, got %d", 1, len(models))
+	}
+
+	var errs []error
+	for _, err := range ReceiveNDJSON[FakeModel](base.New().Get("invalid"), nil) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 2 || errs[0] != nil || errs[1] == nil {
+		t.Errorf("expected a decoding error after the first record, got %v", errs)
+	}
+
+	apiError := new(APIError)
+	for _, err := range ReceiveNDJSON[FakeModel](base.New().Get("failure"), apiError) {
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != 400 {
+			t.Errorf("expected a 400 *HTTPError, got %v", err)
+		}
+	}
+	if apiError.Code != 215 {
+		t.Errorf("expected failureV to be decoded, got %v", apiError)
+	}
+}
+
+func TestReceiveNDJSONFunc(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{\"text\": \"a\"}\n{\"text\": \"b\"}\n")
+	})
+
+	s := New().Client(client).Get("http://example.com/export")
+	var texts []string
+	resp, err := ReceiveNDJSONFunc(s, nil, func(model FakeModel) error {
+		texts = append(texts, model.Text)
+		return nil
+	})
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !reflect.DeepEqual([]string{"a", "b"}, texts) {
+		t.Errorf("expected %v, got %v", []string{"a", "b"}, texts)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	_, err = ReceiveNDJSONFunc(s, nil, func(model FakeModel) error {
+		calls++
+		return errStop
+	})
+	if err != errStop || calls != 1 {
+		t.Errorf("expected %v after %d call, got %v after %d", errStop, 1, err, calls)
+	}
+}
@@ -0,0 +1,192 @@
+package sling
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxExcerptBytes is the maximum number of bytes of a response Body kept by an
+// UnsupportedContentTypeError.
+const maxExcerptBytes = 512
+
+// NegotiatingDecoder is a ResponseDecoder which selects a ResponseDecoder by
+// the response Content-Type. Slings using a NegotiatingDecoder send an
+// Accept header listing its media types, unless an Accept header is set.
+//
+// Media types may be exact (e.g. "application/json"), structured syntax
+// suffixes (e.g. "+json" matches "application/vnd.github+json"), or subtype
+// wildcards (e.g. "text/*"). Exact matches are preferred, then suffixes, then
+// wildcards.
+type NegotiatingDecoder struct {
+	decoders map[string]ResponseDecoder
+	// media types in registration order, for the Accept header
+	order []string
+}
+
+// NewNegotiatingDecoder returns a NegotiatingDecoder which decodes JSON
+// ("application/json" and "+json"), XML ("application/xml", "text/xml",
+// and "+xml"), forms ("application/x-www-form-urlencoded") into *url.Values
+// or *map[string]string, and "text/plain" or "application/octet-stream"
+// into *string, *[]byte, or an io.Writer.
+func NewNegotiatingDecoder() *NegotiatingDecoder {
+	d := &NegotiatingDecoder{decoders: make(map[string]ResponseDecoder)}
+	d.Register(jsonContentType, jsonDecoder{})
+	d.Register("+json", jsonDecoder{})
+	d.Register(xmlContentType, XMLDecoder{})
+	d.Register("text/xml", XMLDecoder{})
+	d.Register("+xml", XMLDecoder{})
+	d.Register(formContentType, formDecoder{})
+	d.Register("text/plain", bytesDecoder{})
+	d.Register(octetStreamContentType, bytesDecoder{})
+	return d
+}
+
+// Register registers the ResponseDecoder for the media type, replacing any
+// existing decoder for the media type. Register should not be called while
+// the NegotiatingDecoder is in use.
+func (d *NegotiatingDecoder) Register(mediaType string, decoder ResponseDecoder) *NegotiatingDecoder {
+	mediaType = strings.ToLower(mediaType)
+	if _, ok := d.decoders[mediaType]; !ok {
+		d.order = append(d.order, mediaType)
+	}
+	d.decoders[mediaType] = decoder
+	return d
+}
+
+// clone returns a copy of the NegotiatingDecoder.
+func (d *NegotiatingDecoder) clone() *NegotiatingDecoder {
+	c := &NegotiatingDecoder{
+		decoders: make(map[string]ResponseDecoder, len(d.decoders)),
+		order:    append([]string(nil), d.order...),
+	}
+	for mediaType, decoder := range d.decoders {
+		c.decoders[mediaType] = decoder
+	}
+	return c
+}
+
+// Accept returns an Accept header value listing the media types which can be
+// decoded.
+func (d *NegotiatingDecoder) Accept() string {
+	var types []string
+	for _, mediaType := range d.order {
+		if strings.HasPrefix(mediaType, "+") || strings.HasSuffix(mediaType, "/*") {
+			continue
+		}
+		types = append(types, mediaType)
+	}
+	return strings.Join(types, ", ")
+}
+
+// Decode decodes the Response Body into the value pointed to by v, using the
+// ResponseDecoder for the response Content-Type. Returns an
+// *UnsupportedContentTypeError if no ResponseDecoder is registered for the
+// Content-Type.
+func (d *NegotiatingDecoder) Decode(resp *http.Response, v interface{}) error {
+	header := resp.Header.Get(contentType)
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err == nil {
+		if decoder := d.lookup(strings.ToLower(mediaType)); decoder != nil {
+			return decoder.Decode(resp, v)
+		}
+	}
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxExcerptBytes))
+	return &UnsupportedContentTypeError{
+		ContentType: header,
+		StatusCode:  resp.StatusCode,
+		Excerpt:     excerpt,
+	}
+}
+
+// lookup returns the ResponseDecoder for the media type, or nil.
+func (d *NegotiatingDecoder) lookup(mediaType string) ResponseDecoder {
+	if decoder, ok := d.decoders[mediaType]; ok {
+		return decoder
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		if decoder, ok := d.decoders[mediaType[i:]]; ok {
+			return decoder
+		}
+	}
+	if i := strings.IndexByte(mediaType, '/'); i >= 0 {
+		if decoder, ok := d.decoders[mediaType[:i]+"/*"]; ok {
+			return decoder
+		}
+	}
+	return nil
+}
+
+// UnsupportedContentTypeError is returned by a NegotiatingDecoder when no
+// ResponseDecoder is registered for the response Content-Type (e.g. an HTML
+// error page from a proxy).
+type UnsupportedContentTypeError struct {
+	// ContentType is the response Content-Type header.
+	ContentType string
+	// StatusCode is the response status code.
+	StatusCode int
+	// Excerpt holds up to the first 512 bytes of the response Body.
+	Excerpt []byte
+}
+
+func (e *UnsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("sling: unsupported response Content-Type %q (status %d): %q", e.ContentType, e.StatusCode, e.Excerpt)
+}
+
+// formDecoder decodes an http response url encoded form.
+type formDecoder struct{}
+
+// Decode decodes the Response Body into v, which must be a *url.Values or
+// *map[string]string.
+func (d formDecoder) Decode(resp *http.Response, v interface{}) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case *url.Values:
+		*v = values
+	case *map[string][]string:
+		*v = values
+	case *map[string]string:
+		*v = make(map[string]string, len(values))
+		for key := range values {
+			(*v)[key] = values.Get(key)
+		}
+	default:
+		return fmt.Errorf("sling: cannot decode form into %T", v)
+	}
+	return nil
+}
+
+// bytesDecoder decodes the raw http response Body.
+type bytesDecoder struct{}
+
+// Decode reads the Response Body into v, which must be a *string, *[]byte,
+// or io.Writer.
+func (d bytesDecoder) Decode(resp *http.Response, v interface{}) error {
+	if w, ok := v.(io.Writer); ok {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case *[]byte:
+		*v = body
+	case *string:
+		*v = string(body)
+	default:
+		return fmt.Errorf("sling: cannot decode %s into %T", resp.Header.Get(contentType), v)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package sling
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNegotiatingDecoder(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/model", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		switch r.URL.Query().Get("type") {
+		case "application/json; charset=utf-8", "application/vnd.github+json":
+			fmt.Fprint(w, `{"text": "Some text", "favorite_count": 24}`)
+		case "application/xml", "text/xml", "application/atom+xml":
+			fmt.Fprint(w, `<response><text>Some text</text><favorite_count>24</favorite_count></response>`)
+		}
+	})
+
+	s := New().Client(client).ResponseDecoder(NewNegotiatingDecoder()).Get("http://example.com/model")
+	types := []string{
+		"application/json; charset=utf-8",
+		"application/vnd.github+json",
+		"application/xml",
+		"text/xml",
+		"application/atom+xml",
+	}
+	for _, mediaType := range types {
+		model := new(FakeModel)
+		_, err := s.New().QueryStruct(setQuery{"type": {mediaType}}).ReceiveSuccess(model)
+		if err != nil {
+			t.Errorf("%s: expected nil, got %v", mediaType, err)
+		}
+		expected := &FakeModel{Text: "Some text", FavoriteCount: 24}
+		if !reflect.DeepEqual(expected, model) {
+			t.Errorf("%s: expected %v, got %v", mediaType, expected, model)
+		}
+	}
+}
+
+func TestNegotiatingDecoder_rawTypes(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", formContentType)
+		fmt.Fprint(w, "access_token=abc&scope=read&scope=write")
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, "plain text")
+	})
+	mux.HandleFunc("/bytes", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", octetStreamContentType)
+		w.Write([]byte{0, 1, 2})
+	})
+
+	s := New().Client(client).Base("http://example.com/").ResponseDecoder(NewNegotiatingDecoder())
+	values := url.Values{}
+	if _, err := s.New().Get("form").ReceiveSuccess(&values); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if expected := (url.Values{"access_token": {"abc"}, "scope": {"read", "write"}}); !reflect.DeepEqual(expected, values) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+	var text string
+	if _, err := s.New().Get("text").ReceiveSuccess(&text); err != nil || text != "plain text" {
+		t.Errorf("expected (plain text, nil), got (%s, %v)", text, err)
+	}
+	var raw []byte
+	if _, err := s.New().Get("bytes").ReceiveSuccess(&raw); err != nil || !bytes.Equal(raw, []byte{0, 1, 2}) {
+		t.Errorf("expected ([0 1 2], nil), got (%v, %v)", raw, err)
+	}
+	var buf bytes.Buffer
+	if _, err := s.New().Get("text").ReceiveSuccess(&buf); err != nil || buf.String() != "plain text" {
+		t.Errorf("expected (plain text, nil), got (%s, %v)", buf.String(), err)
+	}
+}
+
+func TestNegotiatingDecoder_unsupported(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	page := "<html><body>" + strings.Repeat("Bad Gateway ", 100) + "</body></html>"
+	mux.HandleFunc("/proxy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(502)
+		fmt.Fprint(w, page)
+	})
+
+	apiError := new(APIError)
+	_, err := New().Client(client).ResponseDecoder(NewNegotiatingDecoder()).Get("http://example.com/proxy").Receive(nil, apiError)
+	var ctErr *UnsupportedContentTypeError
+	if !errors.As(err, &ctErr) {
+		t.Fatalf("expected an *UnsupportedContentTypeError, got %v", err)
+	}
+	if ctErr.ContentType != "text/html" || ctErr.StatusCode != 502 {
+		t.Errorf("expected text/html 502, got %s %d", ctErr.ContentType, ctErr.StatusCode)
+	}
+	if string(ctErr.Excerpt) != page[:maxExcerptBytes] {
+		t.Errorf("expected a %d byte excerpt, got %q", maxExcerptBytes, ctErr.Excerpt)
+	}
+}
+
+func TestNegotiatingDecoder_accept(t *testing.T) {
+	req, _ := New().ResponseDecoder(NewNegotiatingDecoder()).Request()
+	expected := "application/json, application/xml, text/xml, application/x-www-form-urlencoded, text/plain, application/octet-stream"
+	if accept := req.Header.Get("Accept"); accept != expected {
+		t.Errorf("expected %s, got %s", expected, accept)
+	}
+	req, _ = New().ResponseDecoder(NewNegotiatingDecoder()).Set("Accept", "application/json").Request()
+	if accept := req.Header.Get("Accept"); accept != "application/json" {
+		t.Errorf("expected explicit Accept header to be kept, got %s", accept)
+	}
+	req, _ = New().Request()
+	if accept := req.Header.Get("Accept"); accept != "" {
+		t.Errorf("expected no Accept header with the default decoder, got %s", accept)
+	}
+}
+
+// upperDecoder decodes a response Body into an upper-cased *string.
+type upperDecoder struct{}
+
+func (upperDecoder) Decode(resp *http.Response, v interface{}) error {
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	*(v.(*string)) = strings.ToUpper(buf.String())
+	return nil
+}
+
+func TestRegisterMediaType(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/csv", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		fmt.Fprint(w, "a,b")
+	})
+
+	parent := New().Client(client).Get("http://example.com/csv").ResponseDecoder(NewNegotiatingDecoder())
+	child := parent.New().RegisterMediaType("text/csv", upperDecoder{})
+	var csv string
+	if _, err := child.ReceiveSuccess(&csv); err != nil || csv != "A,B" {
+		t.Errorf("expected (A,B, nil), got (%s, %v)", csv, err)
+	}
+	req, _ := child.Request()
+	if accept := req.Header.Get("Accept"); !strings.HasSuffix(accept, ", text/csv") {
+		t.Errorf("expected Accept header to include text/csv, got %s", accept)
+	}
+	// registering a media type on a child should not modify the parent
+	var ctErr *UnsupportedContentTypeError
+	if _, err := parent.ReceiveSuccess(&csv); !errors.As(err, &ctErr) {
+		t.Errorf("expected an *UnsupportedContentTypeError, got %v", err)
+	}
+	// registering a media type replaces a non-negotiating decoder
+	if _, ok := New().RegisterMediaType("text/csv", upperDecoder{}).responseDecoder.(*NegotiatingDecoder); !ok {
+		t.Errorf("expected a *NegotiatingDecoder")
+	}
+}
@@ -0,0 +1,103 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Device polling intervals (RFC 8628 Section 3.5), which tests may shorten.
+var (
+	// defaultPollInterval is used if the authorization server doesn't set
+	// an interval
+	defaultPollInterval = 5 * time.Second
+	// slowDownInterval is added to the interval on "slow_down" errors
+	slowDownInterval = 5 * time.Second
+)
+
+// DeviceAuth is a device authorization response (RFC 8628 Section 3.2).
+type DeviceAuth struct {
+	// DeviceCode is the device verification code.
+	DeviceCode string `json:"device_code"`
+	// UserCode is the code the user enters at the VerificationURI.
+	UserCode string `json:"user_code"`
+	// VerificationURI is where the user authorizes the device.
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete is the VerificationURI with the UserCode, if
+	// the authorization server provides one.
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	// ExpiresIn is the lifetime of the DeviceCode and UserCode in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+	// Interval is the minimum number of seconds between token requests.
+	Interval int64 `json:"interval"`
+	// expiry of the codes
+	expiry time.Time
+}
+
+// DeviceAuth starts the device authorization grant (RFC 8628). Show the
+// UserCode and VerificationURI to the user and call DeviceAccessToken to wait
+// for them to authorize the device.
+func (c *Config) DeviceAuth(ctx context.Context) (*DeviceAuth, error) {
+	params := url.Values{}
+	if len(c.Scopes) > 0 {
+		params.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	auth := new(DeviceAuth)
+	if err := c.post(ctx, c.DeviceAuthURL, params, auth); err != nil {
+		return nil, err
+	}
+	if auth.DeviceCode == "" {
+		return nil, fmt.Errorf("oauth2: device authorization response is missing device_code")
+	}
+	if auth.ExpiresIn > 0 {
+		auth.expiry = time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	}
+	return auth, nil
+}
+
+// DeviceAccessToken polls the token endpoint until the user authorizes the
+// device and returns the token. It returns an Error if the user denies the
+// request ("access_denied") or the device code expires ("expired_token"),
+// or the context error if the context is done first.
+func (c *Config) DeviceAccessToken(ctx context.Context, auth *DeviceAuth) (*Token, error) {
+	if !auth.expiry.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, auth.expiry)
+		defer cancel()
+	}
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && !time.Now().Before(auth.expiry) {
+				return nil, &Error{Code: "expired_token", Description: "the device code expired"}
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		token, err := c.token(ctx, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {auth.DeviceCode},
+		}, false)
+		var oerr *Error
+		if !errors.As(err, &oerr) {
+			return token, err
+		}
+		switch oerr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += slowDownInterval
+		default:
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func init() {
+	defaultPollInterval = 5 * time.Millisecond
+	slowDownInterval = 5 * time.Millisecond
+}
+
+// deviceGrant returns a device authorization endpoint handler which responds
+// to token requests with the error codes, and then with a token.
+func deviceGrant(t *testing.T, as *authServer, expiresIn int, codes ...string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/device":
+			if r.PostForm.Get("client_id") != "client" || r.PostForm.Get("scope") != "read" {
+				t.Errorf("unexpected device authorization request %v", r.PostForm)
+			}
+			fmt.Fprintf(w, `{"device_code": "device", "user_code": "ABCD-EFGH", "verification_uri": "https://example.com/device", "expires_in": %d}`, expiresIn)
+		case "/token":
+			if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" || r.PostForm.Get("device_code") != "device" {
+				t.Errorf("unexpected device access token request %v", r.PostForm)
+			}
+			if len(codes) == 0 {
+				as.issue(w, 3600)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error": %q}`, codes[0])
+			codes = codes[1:]
+		}
+	}
+}
+
+func TestDeviceAccessToken(t *testing.T) {
+	as, server := newAuthServer(t)
+	as.grant = deviceGrant(t, as, 60, "authorization_pending", "slow_down", "authorization_pending")
+	config := &Config{
+		ClientID:      "client",
+		TokenURL:      server.URL + "/token",
+		DeviceAuthURL: server.URL + "/device",
+		Scopes:        []string{"read"},
+	}
+
+	ctx := context.Background()
+	auth, err := config.DeviceAuth(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if auth.UserCode != "ABCD-EFGH" || auth.VerificationURI != "https://example.com/device" {
+		t.Errorf("unexpected device authorization %+v", auth)
+	}
+	token, err := config.DeviceAccessToken(ctx, auth)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if token.AccessToken != "token-1" || token.RefreshToken != "refresh-1" {
+		t.Errorf("unexpected token %+v", token)
+	}
+	// device authorization, then 4 token requests
+	if len(as.requests) != 5 {
+		t.Errorf("expected %d requests, got %d", 5, len(as.requests))
+	}
+}
+
+func TestDeviceAccessToken_errors(t *testing.T) {
+	as, server := newAuthServer(t)
+	config := &Config{
+		ClientID:      "client",
+		TokenURL:      server.URL + "/token",
+		DeviceAuthURL: server.URL + "/device",
+		Scopes:        []string{"read"},
+	}
+	ctx := context.Background()
+
+	// the user denies the authorization request
+	as.grant = deviceGrant(t, as, 60, "authorization_pending", "access_denied")
+	auth, _ := config.DeviceAuth(ctx)
+	_, err := config.DeviceAccessToken(ctx, auth)
+	var oerr *Error
+	if !errors.As(err, &oerr) || oerr.Code != "access_denied" {
+		t.Errorf("expected access_denied error, got %v", err)
+	}
+
+	// the device code expires
+	as.grant = deviceGrant(t, as, 60, "authorization_pending", "authorization_pending")
+	auth, _ = config.DeviceAuth(ctx)
+	auth.expiry = time.Now().Add(-time.Second)
+	_, err = config.DeviceAccessToken(ctx, auth)
+	if !errors.As(err, &oerr) || oerr.Code != "expired_token" {
+		t.Errorf("expected expired_token error, got %v", err)
+	}
+
+	// the context is canceled
+	auth, _ = config.DeviceAuth(ctx)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = config.DeviceAccessToken(canceled, auth); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
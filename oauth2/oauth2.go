@@ -0,0 +1,246 @@
+/*
+Package oauth2 obtains OAuth2 access tokens with Sling, using the client
+credentials, refresh token, and device authorization grants.
+
+Token requests are form encoded (see Sling.BodyForm) and token responses and
+RFC 6749 error responses are decoded from JSON. Config methods return
+sling.TokenSources which cache tokens and refresh them before they expire.
+Use them to authorize requests with Sling.TokenSource or with the
+sling.TokenAuth Middleware.
+
+	config := &oauth2.Config{
+	    ClientID:     "client-id",
+	    ClientSecret: "client-secret",
+	    TokenURL:     "https://auth.example.com/oauth2/token",
+	    Scopes:       []string{"read"},
+	}
+	base := sling.New().Base("https://api.example.com/").TokenSource(config.ClientCredentials())
+*/
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dghubble/sling"
+)
+
+// refreshEarly is how long before expiry cached tokens are refreshed, which
+// matches Sling.TokenSource.
+const refreshEarly = 30 * time.Second
+
+// AuthStyle is how a client authenticates to the token endpoint.
+type AuthStyle int
+
+const (
+	// AuthStyleHeader sends the client credentials with HTTP Basic
+	// Authentication (RFC 6749 Section 2.3.1).
+	AuthStyleHeader AuthStyle = iota
+	// AuthStyleParams sends the client credentials as client_id and
+	// client_secret form parameters.
+	AuthStyleParams
+)
+
+// Config is an OAuth2 client configuration.
+type Config struct {
+	// ClientID is the client identifier.
+	ClientID string
+	// ClientSecret is the client secret, or empty for public clients.
+	ClientSecret string
+	// TokenURL is the token endpoint URL.
+	TokenURL string
+	// DeviceAuthURL is the device authorization endpoint URL (RFC 8628).
+	DeviceAuthURL string
+	// Scopes are the requested scopes.
+	Scopes []string
+	// AuthStyle is how the client authenticates to the token endpoint.
+	AuthStyle AuthStyle
+	// EndpointParams are additional parameters sent to the token and device
+	// authorization endpoints (e.g. "audience").
+	EndpointParams url.Values
+	// Sling is the base Sling used to send token requests, such as one with a
+	// custom http Client. Defaults to sling.New().
+	Sling *sling.Sling
+}
+
+// Token is an OAuth2 token. Like sling.Token, it is redacted when formatted.
+type Token struct {
+	sling.Token
+	// RefreshToken is used to obtain new tokens, if one was issued.
+	RefreshToken string
+	// Scope is the scope of the access token, if the authorization server
+	// reported it.
+	Scope string
+}
+
+// tokenResponse is a successful token response (RFC 6749 Section 5.1).
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope"`
+}
+
+// Error is an OAuth2 error response (RFC 6749 Section 5.2). Use errors.As to
+// inspect it.
+type Error struct {
+	// StatusCode is the response status code (e.g. 400).
+	StatusCode int `json:"-"`
+	// Code is the error code (e.g. "invalid_grant").
+	Code string `json:"error"`
+	// Description is a human-readable description of the error.
+	Description string `json:"error_description"`
+	// URI identifies a web page with information about the error.
+	URI string `json:"error_uri"`
+}
+
+func (e *Error) Error() string {
+	msg := "oauth2: " + e.Code
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// ClientCredentialsToken requests a token with the client credentials grant
+// (RFC 6749 Section 4.4).
+func (c *Config) ClientCredentialsToken(ctx context.Context) (*Token, error) {
+	return c.token(ctx, url.Values{"grant_type": {"client_credentials"}}, true)
+}
+
+// RefreshToken requests a new token with the refresh token grant (RFC 6749
+// Section 6). If the response doesn't include a new refresh token, the
+// returned Token keeps the given refresh token.
+func (c *Config) RefreshToken(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// ClientCredentials returns a TokenSource which requests tokens with the
+// client credentials grant and caches them until shortly before they expire.
+func (c *Config) ClientCredentials() sling.TokenSource {
+	return sling.ReuseTokenSource(sling.TokenSourceFunc(func(ctx context.Context) (*sling.Token, error) {
+		token, err := c.ClientCredentialsToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &token.Token, nil
+	}), refreshEarly)
+}
+
+// TokenSource returns a TokenSource which returns the token (e.g. from
+// DeviceAccessToken) until it expires, and then refreshes it with its
+// RefreshToken, keeping any new refresh token the authorization server
+// issues. Tokens are cached until shortly before they expire.
+func (c *Config) TokenSource(token *Token) sling.TokenSource {
+	return sling.ReuseTokenSource(&refreshTokenSource{config: c, token: token, refreshToken: token.RefreshToken}, refreshEarly)
+}
+
+// refreshTokenSource refreshes tokens with the refresh token grant.
+type refreshTokenSource struct {
+	config *Config
+
+	mu sync.Mutex
+	// initial token, until it is used or expired
+	token        *Token
+	refreshToken string
+}
+
+func (s *refreshTokenSource) Token(ctx context.Context) (*sling.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token := s.token; token != nil {
+		s.token = nil
+		if token.AccessToken != "" && (token.Expiry.IsZero() || time.Now().Before(token.Expiry)) {
+			return &token.Token, nil
+		}
+	}
+	if s.refreshToken == "" {
+		return nil, fmt.Errorf("oauth2: token expired and no refresh token is set")
+	}
+	token, err := s.config.RefreshToken(ctx, s.refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshToken = token.RefreshToken
+	return &token.Token, nil
+}
+
+// token sends a token request with the params and decodes the token
+// response.
+func (c *Config) token(ctx context.Context, params url.Values, scopes bool) (*Token, error) {
+	if scopes && len(c.Scopes) > 0 {
+		params.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	success := new(tokenResponse)
+	if err := c.post(ctx, c.TokenURL, params, success); err != nil {
+		return nil, err
+	}
+	if success.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2: token response is missing access_token")
+	}
+	token := &Token{
+		Token: sling.Token{
+			AccessToken: success.AccessToken,
+			TokenType:   success.TokenType,
+		},
+		RefreshToken: success.RefreshToken,
+		Scope:        success.Scope,
+	}
+	if success.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(success.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// post sends a form encoded request to the endpoint with the params, client
+// authentication, and EndpointParams, and decodes the JSON response into
+// successV or returns an Error.
+func (c *Config) post(ctx context.Context, endpoint string, params url.Values, successV interface{}) error {
+	for key, values := range c.EndpointParams {
+		params[key] = append(params[key], values...)
+	}
+	base := c.Sling
+	if base == nil {
+		base = sling.New()
+	}
+	s := base.New().Post(endpoint).Set("Accept", "application/json")
+	switch {
+	case c.AuthStyle == AuthStyleHeader && c.ClientSecret != "":
+		s = s.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	case c.AuthStyle == AuthStyleParams && c.ClientSecret != "":
+		params.Set("client_secret", c.ClientSecret)
+		fallthrough
+	default:
+		params.Set("client_id", c.ClientID)
+	}
+
+	failure := new(Error)
+	resp, err := s.BodyForm(params).ReceiveContext(ctx, successV, failure)
+	if failure.Code != "" {
+		failure.StatusCode = resp.StatusCode
+		return failure
+	}
+	if resp != nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		// error responses which aren't RFC 6749 errors
+		return fmt.Errorf("oauth2: POST %s: %s", endpoint, resp.Status)
+	}
+	if err != nil {
+		return fmt.Errorf("oauth2: %w", err)
+	}
+	return nil
+}
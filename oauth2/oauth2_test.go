@@ -0,0 +1,257 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dghubble/sling"
+)
+
+// authServer is a stand-in authorization server which issues numbered
+// tokens.
+type authServer struct {
+	t *testing.T
+	// token endpoint handler for grant types other than client_credentials
+	// and refresh_token
+	grant func(w http.ResponseWriter, r *http.Request)
+
+	mu       sync.Mutex
+	issued   int
+	requests []*http.Request
+}
+
+func newAuthServer(t *testing.T) (*authServer, *httptest.Server) {
+	as := &authServer{t: t}
+	server := httptest.NewServer(as)
+	t.Cleanup(server.Close)
+	return as, server
+}
+
+func (as *authServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		as.t.Errorf("expected nil, got %v", err)
+	}
+	if r.URL.Path == "/api" {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+		return
+	}
+	as.mu.Lock()
+	as.requests = append(as.requests, r)
+	as.mu.Unlock()
+	if r.Header.Get("Accept") != "application/json" {
+		as.t.Errorf("expected Accept %s, got %s", "application/json", r.Header.Get("Accept"))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch grantType := r.PostForm.Get("grant_type"); {
+	case r.URL.Path == "/token" && grantType == "refresh_token" && r.PostForm.Get("refresh_token") == "revoked":
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "refresh token revoked"}`)
+	case r.URL.Path == "/token" && (grantType == "client_credentials" || grantType == "refresh_token"):
+		as.issue(w, 3600)
+	case as.grant != nil:
+		as.grant(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// issue writes a token response with a new token and refresh token.
+func (as *authServer) issue(w http.ResponseWriter, expiresIn int) {
+	as.mu.Lock()
+	as.issued++
+	n := as.issued
+	as.mu.Unlock()
+	fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d, "refresh_token": "refresh-%d", "scope": "read"}`, n, expiresIn, n)
+}
+
+func (as *authServer) lastRequest() *http.Request {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return as.requests[len(as.requests)-1]
+}
+
+func TestClientCredentials(t *testing.T) {
+	as, server := newAuthServer(t)
+	config := &Config{
+		ClientID:       "client id",
+		ClientSecret:   "secret",
+		TokenURL:       server.URL + "/token",
+		Scopes:         []string{"read", "write"},
+		EndpointParams: map[string][]string{"audience": {"api"}},
+	}
+	token, err := config.ClientCredentialsToken(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if token.AccessToken != "token-1" || token.Type() != "Bearer" || token.Scope != "read" {
+		t.Errorf("unexpected token %+v", *token)
+	}
+	if d := time.Until(token.Expiry); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expected expiry in 1h, got %v", d)
+	}
+
+	req := as.lastRequest()
+	if user, pass, _ := req.BasicAuth(); user != "client+id" || pass != "secret" {
+		t.Errorf("expected client credentials %s:%s, got %s:%s", "client+id", "secret", user, pass)
+	}
+	expected := "audience=api&grant_type=client_credentials&scope=read+write"
+	if form := req.PostForm.Encode(); form != expected {
+		t.Errorf("expected %s, got %s", expected, form)
+	}
+}
+
+func TestClientCredentials_authStyleParams(t *testing.T) {
+	as, server := newAuthServer(t)
+	config := &Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		TokenURL:     server.URL + "/token",
+		AuthStyle:    AuthStyleParams,
+	}
+	if _, err := config.ClientCredentialsToken(context.Background()); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	req := as.lastRequest()
+	if _, _, ok := req.BasicAuth(); ok {
+		t.Errorf("expected no Authorization header")
+	}
+	expected := "client_id=client&client_secret=secret&grant_type=client_credentials"
+	if form := req.PostForm.Encode(); form != expected {
+		t.Errorf("expected %s, got %s", expected, form)
+	}
+}
+
+func TestClientCredentials_tokenSource(t *testing.T) {
+	as, server := newAuthServer(t)
+	config := &Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		TokenURL:     server.URL + "/token",
+	}
+
+	// tokens are cached with Sling.TokenSource and the TokenAuth Middleware
+	api := sling.New().Base(server.URL).Path("/api")
+	for _, s := range []*sling.Sling{
+		api.New().TokenSource(config.ClientCredentials()),
+		api.New().Use(sling.TokenAuth(config.ClientCredentials())),
+	} {
+		as.issued = 0
+		for i := 0; i < 3; i++ {
+			resp, err := s.New().ReceiveSuccess(nil)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200 OK, got %v %v", resp, err)
+			}
+		}
+		if as.issued != 1 {
+			t.Errorf("expected %d token, got %d", 1, as.issued)
+		}
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	as, server := newAuthServer(t)
+	config := &Config{
+		ClientID: "client",
+		TokenURL: server.URL + "/token",
+	}
+	src := config.TokenSource(&Token{
+		Token:        sling.Token{AccessToken: "initial", Expiry: time.Now().Add(time.Hour)},
+		RefreshToken: "refresh-0",
+	})
+	token, err := src.Token(context.Background())
+	if err != nil || token.AccessToken != "initial" {
+		t.Fatalf("expected initial token, got %v %v", token, err)
+	}
+	if len(as.requests) != 0 {
+		t.Errorf("expected no token requests, got %d", len(as.requests))
+	}
+
+	// the initial token is refreshed when it's rejected
+	resp, err := sling.New().Get(server.URL + "/api").TokenSource(src).ReceiveSuccess(nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %v %v", resp, err)
+	}
+	if expected := "client_id=client&grant_type=refresh_token&refresh_token=refresh-0"; as.requests[0].PostForm.Encode() != expected {
+		t.Errorf("expected %s, got %s", expected, as.requests[0].PostForm.Encode())
+	}
+	if as.issued != 1 {
+		t.Errorf("expected %d token, got %d", 1, as.issued)
+	}
+}
+
+func TestTokenSource_rotateRefreshToken(t *testing.T) {
+	as, server := newAuthServer(t)
+	config := &Config{
+		ClientID: "client",
+		TokenURL: server.URL + "/token",
+	}
+	// expired initial tokens are refreshed
+	src := &refreshTokenSource{config: config, token: &Token{Token: sling.Token{AccessToken: "expired", Expiry: time.Now()}}, refreshToken: "refresh-0"}
+	for i := 1; i <= 2; i++ {
+		token, err := src.Token(context.Background())
+		if expected := fmt.Sprintf("token-%d", i); err != nil || token.AccessToken != expected {
+			t.Fatalf("expected %s, got %v %v", expected, token, err)
+		}
+		if expected := fmt.Sprintf("refresh-%d", i-1); as.lastRequest().PostForm.Get("refresh_token") != expected {
+			t.Errorf("expected %s, got %s", expected, as.lastRequest().PostForm.Get("refresh_token"))
+		}
+	}
+}
+
+func TestTokenSource_errors(t *testing.T) {
+	_, server := newAuthServer(t)
+	config := &Config{
+		ClientID: "client",
+		TokenURL: server.URL + "/token",
+	}
+	src := config.TokenSource(&Token{Token: sling.Token{AccessToken: "expired", Expiry: time.Now()}})
+	if _, err := src.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "no refresh token") {
+		t.Errorf("expected missing refresh token error, got %v", err)
+	}
+
+	_, err := config.RefreshToken(context.Background(), "revoked")
+	var oerr *Error
+	if !errors.As(err, &oerr) {
+		t.Fatalf("expected an *Error, got %v", err)
+	}
+	expected := &Error{StatusCode: 400, Code: "invalid_grant", Description: "refresh token revoked"}
+	if *oerr != *expected {
+		t.Errorf("expected %+v, got %+v", expected, oerr)
+	}
+	if oerr.Error() != "oauth2: invalid_grant: refresh token revoked" {
+		t.Errorf("unexpected error message %s", oerr.Error())
+	}
+
+	// non-OAuth2 error responses
+	config.TokenURL = server.URL + "/missing"
+	_, err = config.ClientCredentialsToken(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
+
+func TestToken_redacted(t *testing.T) {
+	token := &Token{Token: sling.Token{AccessToken: "access"}, RefreshToken: "refresh"}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if s := fmt.Sprintf(format, token); strings.Contains(s, "access") || strings.Contains(s, "refresh") {
+			t.Errorf("%s: expected tokens to be redacted, got %s", format, s)
+		}
+	}
+	if b, _ := json.Marshal(token); !strings.Contains(string(b), "refresh") {
+		t.Errorf("expected tokens to be JSON encoded, got %s", b)
+	}
+}
@@ -0,0 +1,359 @@
+package sling
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PageStrategy determines how paginated API responses are decoded and how the
+// request for each following page is created.
+type PageStrategy interface {
+	// First returns the Sling for the first page, given a child of the
+	// paginated Sling.
+	First(s *Sling) *Sling
+	// Next decodes the items of a page response into the slice pointed to by
+	// items and returns the Sling for the next page, or nil if the response
+	// was the last page. The paginated Sling s should be extended (with New)
+	// to create the next Sling.
+	Next(s *Sling, resp *http.Response, items interface{}) (*Sling, error)
+}
+
+// Pages iterates over the items of a paginated API.
+type Pages[T any] struct {
+	sling    *Sling
+	strategy PageStrategy
+	maxPages int
+	maxItems int
+}
+
+// Paginate returns Pages which iterate over items of type T from the
+// Sling's paginated API. Each page request extends the Sling, so headers,
+// query structs, and the ResponseDecoder are reused. For example,
+//
+//	pages := sling.Paginate[Issue](githubBase.New().Get(path), sling.LinkHeader())
+//	for issue, err := range pages.MaxItems(100).All() {
+//	    ...
+//	}
+func Paginate[T any](s *Sling, strategy PageStrategy) *Pages[T] {
+	return &Pages[T]{
+		sling:    s.New(),
+		strategy: strategy,
+	}
+}
+
+// MaxPages sets the maximum number of pages to request. Zero means no limit.
+func (p *Pages[T]) MaxPages(n int) *Pages[T] {
+	p.maxPages = n
+	return p
+}
+
+// MaxItems sets the maximum number of items to yield. Zero means no limit.
+func (p *Pages[T]) MaxItems(n int) *Pages[T] {
+	p.maxItems = n
+	return p
+}
+
+// All returns an iterator over the items of each page. Errors creating or
+// sending requests, non-2XX responses (as an *HTTPError), or decoding errors
+// are yielded last.
+func (p *Pages[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		err := p.each(func(item T) bool {
+			return yield(item, nil)
+		})
+		if err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// ForEach calls fn with the items of each page, stopping if fn returns an
+// error. Returns the error from fn or any error requesting pages.
+func (p *Pages[T]) ForEach(fn func(item T) error) error {
+	var fnErr error
+	err := p.each(func(item T) bool {
+		fnErr = fn(item)
+		return fnErr == nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+// each requests pages, calling yield with each item until yield returns
+// false or a limit is reached.
+func (p *Pages[T]) each(yield func(T) bool) error {
+	next := p.strategy.First(p.sling.New())
+	pages, items := 0, 0
+	for next != nil {
+		if p.maxPages > 0 && pages >= p.maxPages || p.maxItems > 0 && items >= p.maxItems {
+			return nil
+		}
+		var page []T
+		var err error
+		next, err = p.page(next, &page)
+		if err != nil {
+			return err
+		}
+		pages++
+		for _, item := range page {
+			if p.maxItems > 0 && items >= p.maxItems {
+				return nil
+			}
+			items++
+			if !yield(item) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// page sends the request for a page, decodes its items, and returns the
+// Sling for the next page.
+func (p *Pages[T]) page(s *Sling, page *[]T) (*Sling, error) {
+	req, err := s.Request()
+	if err != nil {
+		return nil, err
+	}
+	resp, attempts, err := s.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	defer s.drain(resp.Body)
+
+	verifying := s.wrapResponseBody(req, resp)
+	s.decompressBody(req, resp)
+	s.limitBody(resp)
+	if !isSuccess(resp.StatusCode) {
+		return nil, withAttempts(attempts, newHTTPError(req, resp, s.responseDecoder, nil, s.statusErrors))
+	}
+	if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		return nil, nil
+	}
+	next, err := p.strategy.Next(p.sling, resp, page)
+	if err != nil {
+		return nil, err
+	}
+	return next, verifyBody(resp, verifying)
+}
+
+// linkHeaderStrategy follows RFC 8288 Link headers.
+type linkHeaderStrategy struct{}
+
+// LinkHeader returns a PageStrategy for APIs which respond with a list of
+// items and an RFC 8288 Link header with the URL of the next page (e.g.
+// `Link: <https://api.github.com/issues?page=2>; rel="next"`). Next page
+// URLs are requested as-is, since they already include query parameters.
+func LinkHeader() PageStrategy {
+	return linkHeaderStrategy{}
+}
+
+func (linkHeaderStrategy) First(s *Sling) *Sling {
+	return s
+}
+
+func (linkHeaderStrategy) Next(s *Sling, resp *http.Response, items interface{}) (*Sling, error) {
+	if err := s.responseDecoder.Decode(resp, items); err != nil {
+		return nil, err
+	}
+	link, ok := parseLinkHeader(resp.Header.Values("Link"))["next"]
+	if !ok {
+		return nil, nil
+	}
+	nextURL, err := requestURL(resp).Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	next := s.New().Base(nextURL.String())
+	next.queryStructs = nil
+	return next, nil
+}
+
+// requestURL returns the URL of the request for the response.
+func requestURL(resp *http.Response) *url.URL {
+	if resp.Request == nil {
+		return &url.URL{}
+	}
+	return resp.Request.URL
+}
+
+// parseLinkHeader parses RFC 8288 Link header values into a map from
+// relation types to target URLs.
+func parseLinkHeader(values []string) map[string]string {
+	links := make(map[string]string)
+	for _, value := range values {
+		for value != "" {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			target := value[start+1 : end]
+			value = value[end+1:]
+			// link params extend to the next link
+			params := value
+			if next := strings.IndexByte(value, '<'); next >= 0 {
+				params, value = value[:next], value[next:]
+			} else {
+				value = ""
+			}
+			for _, param := range strings.Split(params, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				val = strings.Trim(val, `", `)
+				for _, rel := range strings.Fields(val) {
+					if _, ok := links[strings.ToLower(rel)]; !ok {
+						links[strings.ToLower(rel)] = target
+					}
+				}
+			}
+		}
+	}
+	return links
+}
+
+// cursorStrategy reads items and a cursor from a JSON response body.
+type cursorStrategy struct {
+	itemsField  string
+	cursorField string
+	cursorParam string
+}
+
+// Cursor returns a PageStrategy for APIs which respond with a JSON object
+// holding a list of items and a cursor for the next page. Fields are
+// dot-separated paths (e.g. "data.items" and "meta.next_cursor"). The cursor
+// is sent as the cursorParam query parameter for the next page. An empty or
+// missing cursor ends pagination. The Sling's ResponseDecoder must decode into a
+// *json.RawMessage.
+func Cursor(itemsField, cursorField, cursorParam string) PageStrategy {
+	return cursorStrategy{
+		itemsField:  itemsField,
+		cursorField: cursorField,
+		cursorParam: cursorParam,
+	}
+}
+
+func (c cursorStrategy) First(s *Sling) *Sling {
+	return s
+}
+
+func (c cursorStrategy) Next(s *Sling, resp *http.Response, items interface{}) (*Sling, error) {
+	var body json.RawMessage
+	if err := s.responseDecoder.Decode(resp, &body); err != nil {
+		return nil, err
+	}
+	rawItems, err := jsonField(body, c.itemsField)
+	if err != nil {
+		return nil, err
+	}
+	if rawItems != nil {
+		if err := json.Unmarshal(rawItems, items); err != nil {
+			return nil, err
+		}
+	}
+	rawCursor, err := jsonField(body, c.cursorField)
+	if err != nil || rawCursor == nil {
+		return nil, err
+	}
+	var cursor interface{}
+	if err := json.Unmarshal(rawCursor, &cursor); err != nil {
+		return nil, err
+	}
+	switch cursor := cursor.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if cursor == "" {
+			return nil, nil
+		}
+		return s.New().QueryStruct(setQuery{c.cursorParam: {cursor}}), nil
+	default:
+		return s.New().QueryStruct(setQuery{c.cursorParam: {string(rawCursor)}}), nil
+	}
+}
+
+// jsonField returns the raw JSON value at the dot-separated path, or nil if
+// the path is missing.
+func jsonField(raw json.RawMessage, path string) (json.RawMessage, error) {
+	if path == "" {
+		return raw, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, fmt.Errorf("sling: decoding field %q: %w", path, err)
+		}
+		var ok bool
+		if raw, ok = object[key]; !ok {
+			return nil, nil
+		}
+	}
+	return raw, nil
+}
+
+// offsetStrategy sends offset (or page number) and limit query parameters.
+type offsetStrategy struct {
+	offsetParam string
+	limitParam  string
+	limit       int
+	// page numbers, rather than item offsets
+	numbered bool
+	first    int
+}
+
+// Offset returns a PageStrategy for APIs which respond with a list of items
+// and accept offset and limit query parameters. Pagination ends when a page
+// has fewer than limit items.
+func Offset(offsetParam, limitParam string, limit int) PageStrategy {
+	return offsetStrategy{offsetParam: offsetParam, limitParam: limitParam, limit: limit}
+}
+
+// PageNumber returns a PageStrategy for APIs which respond with a list of
+// items and accept page number and page size query parameters (e.g. "page"
+// and "per_page"), with pages numbered from 1. Pagination ends when a page
+// has fewer than perPage items.
+func PageNumber(pageParam, perPageParam string, perPage int) PageStrategy {
+	return offsetStrategy{offsetParam: pageParam, limitParam: perPageParam, limit: perPage, numbered: true, first: 1}
+}
+
+func (o offsetStrategy) First(s *Sling) *Sling {
+	return s.QueryStruct(o.query(o.first))
+}
+
+func (o offsetStrategy) Next(s *Sling, resp *http.Response, items interface{}) (*Sling, error) {
+	if err := s.responseDecoder.Decode(resp, items); err != nil {
+		return nil, err
+	}
+	n := reflect.ValueOf(items).Elem().Len()
+	if n == 0 || n < o.limit {
+		return nil, nil
+	}
+	current, err := strconv.Atoi(requestURL(resp).Query().Get(o.offsetParam))
+	if err != nil {
+		current = o.first
+	}
+	if o.numbered {
+		return s.New().QueryStruct(o.query(current + 1)), nil
+	}
+	return s.New().QueryStruct(o.query(current + n)), nil
+}
+
+func (o offsetStrategy) query(offset int) setQuery {
+	query := setQuery{o.offsetParam: {strconv.Itoa(offset)}}
+	if o.limitParam != "" && o.limit > 0 {
+		query[o.limitParam] = []string{strconv.Itoa(o.limit)}
+	}
+	return query
+}
@@ -0,0 +1,225 @@
+package sling
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type pageParams struct {
+	State string `url:"state"`
+}
+
+func TestPaginate_linkHeader(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/issues", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("expected headers to be reused on each page")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch page := r.URL.Query().Get("page"); page {
+		case "":
+			assertQuery(t, map[string]string{"state": "open"}, r)
+			w.Header().Set("Link", `<http://example.com/issues?page=2&state=open>; rel="next", <http://example.com/issues?page=3&state=open>; rel="last"`)
+			fmt.Fprint(w, `[{"text": "a"}, {"text": "b"}]`)
+		case "2":
+			assertQuery(t, map[string]string{"state": "open", "page": "2"}, r)
+			w.Header().Set("Link", `</issues?page=3&state=open>; rel="next"`)
+			fmt.Fprint(w, `[{"text": "c"}]`)
+		case "3":
+			fmt.Fprint(w, `[{"text": "d"}]`)
+		}
+	})
+
+	s := New().Client(client).Set("Authorization", "Bearer token").Get("http://example.com/issues").QueryStruct(pageParams{State: "open"})
+	var texts []string
+	for model, err := range Paginate[FakeModel](s, LinkHeader()).All() {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		texts = append(texts, model.Text)
+	}
+	if expected := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(expected, texts) {
+		t.Errorf("expected %v, got %v", expected, texts)
+	}
+
+	texts = nil
+	err := Paginate[FakeModel](s, LinkHeader()).MaxItems(3).ForEach(func(model FakeModel) error {
+		texts = append(texts, model.Text)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(expected, texts) {
+		t.Errorf("expected %v, got %v", expected, texts)
+	}
+}
+
+func TestPaginate_cursor(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var requests int
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch cursor := r.URL.Query().Get("cursor"); cursor {
+		case "":
+			fmt.Fprint(w, `{"data": {"items": [{"text": "a"}]}, "meta": {"next": "c1"}}`)
+		case "c1":
+			fmt.Fprint(w, `{"data": {"items": [{"text": "b"}]}, "meta": {"next": "c2"}}`)
+		case "c2":
+			fmt.Fprint(w, `{"data": {"items": [{"text": "c"}]}, "meta": {"next": null}}`)
+		}
+	})
+
+	s := New().Client(client).Get("http://example.com/events")
+	pages := Paginate[FakeModel](s, Cursor("data.items", "meta.next", "cursor"))
+	var texts []string
+	err := pages.ForEach(func(model FakeModel) error {
+		texts = append(texts, model.Text)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(expected, texts) {
+		t.Errorf("expected %v, got %v", expected, texts)
+	}
+
+	requests = 0
+	for range pages.MaxPages(2).All() {
+	}
+	if requests != 2 {
+		t.Errorf("expected %d requests, got %d", 2, requests)
+	}
+}
+
+func TestPaginate_pageNumber(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var requests int
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		assertQuery(t, map[string]string{"state": "open", "page": strconv.Itoa(page), "per_page": "2"}, r)
+		w.Header().Set("Content-Type", "application/json")
+		switch page {
+		case 1:
+			fmt.Fprint(w, `[{"text": "a"}, {"text": "b"}]`)
+		case 2:
+			fmt.Fprint(w, `[{"text": "c"}]`)
+		default:
+			t.Errorf("unexpected page %d", page)
+		}
+	})
+
+	s := New().Client(client).Get("http://example.com/items").QueryStruct(pageParams{State: "open"})
+	var texts []string
+	for model, err := range Paginate[FakeModel](s, PageNumber("page", "per_page", 2)).All() {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		texts = append(texts, model.Text)
+	}
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(expected, texts) {
+		t.Errorf("expected %v, got %v", expected, texts)
+	}
+
+	// pages after the MaxItems aren't requested
+	requests, texts = 0, nil
+	for model, err := range Paginate[FakeModel](s, PageNumber("page", "per_page", 2)).MaxItems(2).All() {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		texts = append(texts, model.Text)
+	}
+	if expected := []string{"a", "b"}; !reflect.DeepEqual(expected, texts) {
+		t.Errorf("expected %v, got %v", expected, texts)
+	}
+	if requests != 1 {
+		t.Errorf("expected %d requests, got %d", 1, requests)
+	}
+}
+
+func TestPaginate_offset(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var offsets []string
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		offset := r.URL.Query().Get("offset")
+		offsets = append(offsets, offset)
+		w.Header().Set("Content-Type", "application/json")
+		if offset == "4" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[{"text": "a"}, {"text": "b"}]`)
+	})
+
+	s := New().Client(client).Get("http://example.com/items")
+	count := 0
+	for _, err := range Paginate[FakeModel](s, Offset("offset", "limit", 2)).All() {
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Errorf("expected %d items, got %d", 4, count)
+	}
+	if expected := []string{"0", "2", "4"}; !reflect.DeepEqual(expected, offsets) {
+		t.Errorf("expected %v, got %v", expected, offsets)
+	}
+}
+
+func TestPaginate_failure(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	})
+
+	s := New().Client(client).Get("http://example.com/items")
+	var lastErr error
+	for _, err := range Paginate[FakeModel](s, LinkHeader()).All() {
+		lastErr = err
+	}
+	var httpErr *HTTPError
+	if !errors.As(lastErr, &httpErr) || httpErr.StatusCode != 500 {
+		t.Errorf("expected a 500 *HTTPError, got %v", lastErr)
+	}
+
+	errStop := errors.New("stop")
+	err := Paginate[FakeModel](s, LinkHeader()).ForEach(func(FakeModel) error { return errStop })
+	if !errors.As(err, &httpErr) {
+		t.Errorf("expected a *HTTPError, got %v", err)
+	}
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	cases := []struct {
+		values   []string
+		expected map[string]string
+	}{
+		{nil, map[string]string{}},
+		{
+			[]string{`<https://a.io/?page=2>; rel="next", <https://a.io/?page=5>; rel="last"`},
+			map[string]string{"next": "https://a.io/?page=2", "last": "https://a.io/?page=5"},
+		},
+		{
+			[]string{`<https://a.io/1>; title="x"; rel="prev first"`, `<https://a.io/3>;rel=next`},
+			map[string]string{"prev": "https://a.io/1", "first": "https://a.io/1", "next": "https://a.io/3"},
+		},
+		{[]string{`malformed`}, map[string]string{}},
+	}
+	for _, c := range cases {
+		if links := parseLinkHeader(c.values); !reflect.DeepEqual(c.expected, links) {
+			t.Errorf("expected %v, got %v", c.expected, links)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package sling
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// QueryEncoder is implemented by types which encode themselves as url query
+// parameters. QueryEncoders may be passed to QueryStruct.
+type QueryEncoder interface {
+	EncodeQuery() (url.Values, error)
+}
+
+// ArrayFormat is the format of url query parameters with multiple values.
+// Query parameters are merged into url.Values before they're formatted, so
+// a parameter with a single value (including a one element slice) is always
+// sent as a scalar (e.g. "a=1"). To send a one element array, use a key
+// which already ends in "]" (e.g. "a[]"), which is sent as is.
+type ArrayFormat int
+
+const (
+	// ArrayRepeat repeats the key for each value (e.g. "a=1&a=2").
+	ArrayRepeat ArrayFormat = iota
+	// ArrayComma joins values with commas, which are escaped (e.g.
+	// "a=1%2C2").
+	ArrayComma
+	// ArrayBrackets repeats the key with brackets for each value (e.g.
+	// "a[]=1&a[]=2").
+	ArrayBrackets
+	// ArrayIndexed repeats the key with the index of each value (e.g.
+	// "a[0]=1&a[1]=2").
+	ArrayIndexed
+)
+
+// setQuery holds url query values which replace existing values for their
+// keys.
+type setQuery url.Values
+
+// addQuery holds url query values which are added to existing values for
+// their keys.
+type addQuery url.Values
+
+// delQuery holds url query keys whose values are removed.
+type delQuery []string
+
+// queryValues returns a copy of the url.Values.
+func queryValues(values url.Values) url.Values {
+	c := make(url.Values, len(values))
+	for key, vs := range values {
+		c[key] = append([]string(nil), vs...)
+	}
+	return c
+}
+
+// formatArrays returns the url.Values with keys with multiple values in the
+// ArrayFormat. Keys which already end in "]" are not changed.
+func formatArrays(values url.Values, format ArrayFormat) url.Values {
+	if format == ArrayRepeat {
+		return values
+	}
+	formatted := make(url.Values, len(values))
+	for key, vs := range values {
+		if len(vs) < 2 || strings.HasSuffix(key, "]") {
+			formatted[key] = vs
+			continue
+		}
+		switch format {
+		case ArrayComma:
+			formatted[key] = []string{strings.Join(vs, ",")}
+		case ArrayBrackets:
+			formatted[key+"[]"] = vs
+		case ArrayIndexed:
+			for i, v := range vs {
+				formatted[key+"["+strconv.Itoa(i)+"]"] = []string{v}
+			}
+		default:
+			formatted[key] = vs
+		}
+	}
+	return formatted
+}
@@ -0,0 +1,75 @@
+package sling
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+// fakeQueryEncoder encodes a single query parameter.
+type fakeQueryEncoder struct {
+	key   string
+	value string
+}
+
+func (e fakeQueryEncoder) EncodeQuery() (url.Values, error) {
+	if e.key == "" {
+		return nil, errors.New("missing key")
+	}
+	return url.Values{e.key: {e.value}}, nil
+}
+
+func TestQueryParams(t *testing.T) {
+	values := url.Values{"tag": {"a", "b"}}
+	base := New().Base("http://a.io/?token=abc&debug=1").QueryParam("page", "1").QueryValues(values)
+	values.Add("tag", "c")
+
+	cases := []struct {
+		sling       *Sling
+		expectedURL string
+	}{
+		{base.New(), "http://a.io/?debug=1&page=1&tag=a&tag=b&token=abc"},
+		{base.New().QueryParam("page", "2"), "http://a.io/?debug=1&page=1&page=2&tag=a&tag=b&token=abc"},
+		{base.New().SetQueryParam("page", "2").DelQueryParam("debug"), "http://a.io/?page=2&tag=a&tag=b&token=abc"},
+		{base.New().DelQueryParam("tag").QueryParam("tag", "c"), "http://a.io/?debug=1&page=1&tag=c&token=abc"},
+		{base.New().QueryStruct(fakeQueryEncoder{"q", "go"}).DelQueryParam("token"), "http://a.io/?debug=1&page=1&q=go&tag=a&tag=b"},
+		{base.New().QueryArrayFormat(ArrayComma), "http://a.io/?debug=1&page=1&tag=a%2Cb&token=abc"},
+		{base.New().QueryArrayFormat(ArrayBrackets), "http://a.io/?debug=1&page=1&tag%5B%5D=a&tag%5B%5D=b&token=abc"},
+		{base.New().QueryArrayFormat(ArrayIndexed), "http://a.io/?debug=1&page=1&tag%5B0%5D=a&tag%5B1%5D=b&token=abc"},
+		// single values are scalars, unless their key ends in "]"
+		{New().Base("http://a.io/").QueryValues(url.Values{"ids": {"1"}, "tags[]": {"a"}}).QueryArrayFormat(ArrayBrackets), "http://a.io/?ids=1&tags%5B%5D=a"},
+	}
+	for _, c := range cases {
+		req, err := c.sling.Request()
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+			continue
+		}
+		if req.URL.String() != c.expectedURL {
+			t.Errorf("expected %s, got %s", c.expectedURL, req.URL.String())
+		}
+	}
+
+	_, err := New().Get("http://a.io/").QueryStruct(fakeQueryEncoder{}).Request()
+	if err == nil || err.Error() != "missing key" {
+		t.Errorf("expected QueryEncoder error, got %v", err)
+	}
+}
+
+func TestFormatArrays(t *testing.T) {
+	values := url.Values{"a": {"1", "2"}, "b": {"3"}, "c[]": {"4", "5"}}
+	cases := []struct {
+		format   ArrayFormat
+		expected string
+	}{
+		{ArrayRepeat, "a=1&a=2&b=3&c%5B%5D=4&c%5B%5D=5"},
+		{ArrayComma, "a=1%2C2&b=3&c%5B%5D=4&c%5B%5D=5"},
+		{ArrayBrackets, "a%5B%5D=1&a%5B%5D=2&b=3&c%5B%5D=4&c%5B%5D=5"},
+		{ArrayIndexed, "a%5B0%5D=1&a%5B1%5D=2&b=3&c%5B%5D=4&c%5B%5D=5"},
+	}
+	for _, c := range cases {
+		if encoded := formatArrays(values, c.format).Encode(); encoded != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, encoded)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package sling
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 )
 
@@ -20,3 +23,27 @@ type jsonDecoder struct {
 func (d jsonDecoder) Decode(resp *http.Response, v interface{}) error {
 	return json.NewDecoder(resp.Body).Decode(v)
 }
+
+// contextReadCloser wraps a response Body so that read errors caused by the
+// request's context being done wrap the context's error.
+type contextReadCloser struct {
+	ctx context.Context
+	rc  io.ReadCloser
+}
+
+func (r *contextReadCloser) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.rc.Read(p)
+	if err != nil && err != io.EOF {
+		if ctxErr := r.ctx.Err(); ctxErr != nil && err != ctxErr {
+			return n, fmt.Errorf("%w: %v", ctxErr, err)
+		}
+	}
+	return n, err
+}
+
+func (r *contextReadCloser) Close() error {
+	return r.rc.Close()
+}
@@ -0,0 +1,279 @@
+package sling
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDrainRetryBytes is the maximum number of bytes read from the Body of a
+// response which will be retried, to allow re-using the connection.
+const maxDrainRetryBytes = 4 << 10
+
+// RetryPolicy configures which requests are retried and how long to wait
+// between attempts. Zero valued fields use the defaults of
+// DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	MaxAttempts int
+	// StatusCodes are the response status codes which are retried.
+	StatusCodes []int
+	// Methods are the request methods which are retried. Defaults to the
+	// idempotent methods.
+	Methods []string
+	// MinBackoff is the backoff before the first retry, which doubles for
+	// each subsequent retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps the backoff between attempts. Responses with a
+	// Retry-After delay longer than MaxBackoff aren't retried, since the
+	// server wouldn't accept an earlier attempt.
+	MaxBackoff time.Duration
+	// SkipNetworkErrors disables retrying requests which fail without a
+	// response (e.g. connection resets).
+	SkipNetworkErrors bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy which makes up to 3 attempts of
+// idempotent requests which fail with network errors or 429, 502, 503, or
+// 504 responses, with backoffs between 100ms and 10s.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		StatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		Methods: []string{
+			http.MethodGet,
+			http.MethodHead,
+			http.MethodOptions,
+			http.MethodTrace,
+			http.MethodPut,
+			http.MethodDelete,
+		},
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	}
+}
+
+// withDefaults returns a copy of the policy with zero fields set to the
+// defaults.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if len(p.StatusCodes) == 0 {
+		p.StatusCodes = defaults.StatusCodes
+	}
+	if len(p.Methods) == 0 {
+		p.Methods = defaults.Methods
+	}
+	if p.MinBackoff <= 0 {
+		p.MinBackoff = defaults.MinBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaults.MaxBackoff
+	}
+	return p
+}
+
+func (p RetryPolicy) retryMethod(method string) bool {
+	for _, m := range p.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) retryStatus(code int) bool {
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the capped exponential backoff with full jitter before the
+// given retry (starting at 1). A Retry-After header on the response replaces
+// the backoff, or returns false if its delay exceeds the MaxBackoff.
+func (p RetryPolicy) backoff(retry int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return delay, delay <= p.MaxBackoff
+		}
+	}
+	ceiling := p.MaxBackoff
+	if shift := retry - 1; shift < 32 {
+		if exp := p.MinBackoff << uint(shift); exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1)), true
+}
+
+// retryAfter parses a Retry-After header value in delay-seconds or HTTP-date
+// form.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// RetryError is returned when a retried request fails. It reports the error
+// of every attempt. If the last attempt of a request sent by a Sling received
+// a response (e.g. a 503), the response is decoded and the RetryError reports
+// the earlier attempts.
+type RetryError struct {
+	// Errors holds the error of each attempt, in order.
+	Errors []error
+}
+
+func (e *RetryError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = fmt.Sprintf("attempt %d: %v", i+1, err)
+	}
+	return fmt.Sprintf("sling: request failed after %d attempts: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of every attempt.
+func (e *RetryError) Unwrap() []error {
+	return e.Errors
+}
+
+// retryDoer is a Doer which retries requests according to a RetryPolicy.
+type retryDoer struct {
+	next   Doer
+	policy RetryPolicy
+	// attempts records the errors of every attempt when the last attempt
+	// receives a retryable response, if non-nil
+	attempts *RetryError
+}
+
+// NewRetryDoer returns a Doer which sends requests with the given Doer,
+// retrying them according to the policy. Requests with a Body are only
+// retried if their GetBody function is set (Requests created by a Sling
+// re-obtain the body from the BodyProvider). If a nil policy is given, the
+// DefaultRetryPolicy will be used.
+func NewRetryDoer(doer Doer, policy *RetryPolicy) Doer {
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+	return &retryDoer{next: doer, policy: policy.withDefaults()}
+}
+
+// Do sends the request, retrying failed attempts. If an attempt receives a
+// response which isn't retried or the last attempt receives a response, the
+// response is returned. Otherwise, a *RetryError reporting each attempt is
+// returned.
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if !rewindable || !d.policy.retryMethod(req.Method) {
+		return d.next.Do(req)
+	}
+
+	ctx := req.Context()
+	var errs []error
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, retryErr(append(errs, err))
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := d.next.Do(req)
+		if err != nil {
+			errs = append(errs, err)
+			if d.policy.SkipNetworkErrors || ctx.Err() != nil || attempt >= d.policy.MaxAttempts {
+				return nil, retryErr(errs)
+			}
+		} else if !d.policy.retryStatus(resp.StatusCode) {
+			return resp, nil
+		} else {
+			errs = append(errs, fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status))
+		}
+
+		delay, ok := d.policy.backoff(attempt, resp)
+		if resp != nil {
+			if !ok || attempt >= d.policy.MaxAttempts {
+				// return the last response, recording any earlier attempts
+				if d.attempts != nil && len(errs) > 1 {
+					d.attempts.Errors = errs
+				}
+				return resp, nil
+			}
+			io.CopyN(io.Discard, resp.Body, maxDrainRetryBytes)
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, retryErr(append(errs, err))
+		}
+	}
+}
+
+// retryErr returns the only error of a single attempt or a RetryError
+// reporting multiple attempts.
+func retryErr(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return &RetryError{Errors: errs}
+}
+
+// withAttempts returns the error of a request whose last attempt received a
+// response: the error of handling the response, reported after the earlier
+// attempts if the request was retried.
+func withAttempts(attempts *RetryError, err error) error {
+	if attempts == nil {
+		return err
+	}
+	if err == nil {
+		return attempts
+	}
+	// replace the last attempt's status error
+	n := len(attempts.Errors) - 1
+	errs := append(attempts.Errors[:n:n], err)
+	return &RetryError{Errors: errs}
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,272 @@
+package sling
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetryPolicy retries with short backoffs for testing purposes.
+func fastRetryPolicy() *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MinBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+// errDoer is a Doer which always fails with err.
+type errDoer struct {
+	err   error
+	calls int32
+}
+
+func (d *errDoer) Do(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&d.calls, 1)
+	return nil, d.err
+}
+
+func TestRetry_statusCodes(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var calls int32
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		assertMethod(t, "PUT", r)
+		body, _ := io.ReadAll(r.Body)
+		if got := strings.TrimSpace(string(body)); got != `{"text":"note","favorite_count":12}` {
+			t.Errorf("attempt %d: unexpected body %q", atomic.LoadInt32(&calls)+1, got)
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"text": "Some text"}`)
+	})
+
+	model := new(FakeModel)
+	resp, err := New().Client(client).Retry(fastRetryPolicy()).Put("http://example.com/flaky").BodyJSON(modelA).ReceiveSuccess(model)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("expected %d, got %d", 200, resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("expected %d attempts, got %d", 3, calls)
+	}
+	if model.Text != "Some text" {
+		t.Errorf("expected %s, got %s", "Some text", model.Text)
+	}
+}
+
+func TestRetry_exhaustedReturnsLastResponse(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var calls int32
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, `{"message": "bad gateway", "code": 502}`)
+	})
+
+	apiError := new(APIError)
+	resp, err := New().Client(client).Retry(fastRetryPolicy()).Get("http://example.com/down").Receive(nil, apiError)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || len(retryErr.Errors) != 3 {
+		t.Fatalf("expected a *RetryError reporting 3 attempts, got %v", err)
+	}
+	if resp.StatusCode != 502 || apiError.Code != 502 {
+		t.Errorf("expected last 502 response to be decoded, got %d %v", resp.StatusCode, apiError)
+	}
+	if calls != 3 {
+		t.Errorf("expected %d attempts, got %d", 3, calls)
+	}
+
+	// with ErrorOnFailure, the last attempt reports the HTTPError
+	_, err = New().Client(client).Retry(fastRetryPolicy()).Get("http://example.com/down").ErrorOnFailure().Receive(nil, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &retryErr) || len(retryErr.Errors) != 3 || !errors.As(retryErr.Errors[2], &httpErr) {
+		t.Errorf("expected a *RetryError ending with an *HTTPError, got %v", err)
+	}
+
+	// streamed responses report every attempt too
+	_, err = New().Client(client).Retry(fastRetryPolicy()).Get("http://example.com/down").ReceiveStream(nil)
+	if !errors.As(err, &retryErr) || !errors.As(err, &httpErr) {
+		t.Errorf("expected a *RetryError and *HTTPError, got %v", err)
+	}
+}
+
+func TestNewRetryDoer_exhaustedReturnsLastResponse(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var calls int32
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	// like http.Client, the Doer returns the last response without an error
+	req, _ := http.NewRequest("GET", "http://example.com/down", nil)
+	resp, err := NewRetryDoer(client, fastRetryPolicy()).Do(req)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 503 {
+		t.Errorf("expected %d, got %d", 503, resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("expected %d attempts, got %d", 3, calls)
+	}
+}
+
+func TestRetry_nonIdempotentMethod(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var calls int32
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	resp, err := New().Client(client).Retry(fastRetryPolicy()).Post("http://example.com/submit").BodyJSON(modelA).Receive(nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if resp.StatusCode != 503 {
+		t.Errorf("expected %d, got %d", 503, resp.StatusCode)
+	}
+	if calls != 1 {
+		t.Errorf("expected POST to be sent once, got %d attempts", calls)
+	}
+}
+
+func TestRetry_networkErrors(t *testing.T) {
+	netErr := errors.New("connection reset by peer")
+	doer := &errDoer{err: netErr}
+	_, err := New().Doer(doer).Retry(fastRetryPolicy()).Get("http://example.com/").Receive(nil, nil)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("expected a *RetryError, got %v", err)
+	}
+	if len(retryErr.Errors) != 3 {
+		t.Errorf("expected %d attempt errors, got %d", 3, len(retryErr.Errors))
+	}
+	if !errors.Is(err, netErr) {
+		t.Errorf("expected error to wrap %v", netErr)
+	}
+	if doer.calls != 3 {
+		t.Errorf("expected %d attempts, got %d", 3, doer.calls)
+	}
+
+	policy := fastRetryPolicy()
+	policy.SkipNetworkErrors = true
+	doer = &errDoer{err: netErr}
+	_, err = New().Doer(doer).Retry(policy).Get("http://example.com/").Receive(nil, nil)
+	if err != netErr {
+		t.Errorf("expected %v, got %v", netErr, err)
+	}
+	if doer.calls != 1 {
+		t.Errorf("expected %d attempts, got %d", 1, doer.calls)
+	}
+}
+
+func TestRetry_retryAfter(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var calls int32
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	policy := fastRetryPolicy()
+	policy.MinBackoff = time.Hour
+	policy.MaxBackoff = time.Hour
+	resp, err := New().Client(client).Retry(policy).Get("http://example.com/limited").Receive(nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if resp.StatusCode != 204 {
+		t.Errorf("expected %d, got %d", 204, resp.StatusCode)
+	}
+}
+
+func TestRetry_retryAfterBeyondMaxBackoff(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	var calls int32
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	// the first response is returned rather than retried too early
+	resp, err := New().Client(client).Retry(fastRetryPolicy()).Get("http://example.com/limited").Receive(nil, nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if resp.StatusCode != 429 {
+		t.Errorf("expected %d, got %d", 429, resp.StatusCode)
+	}
+	if calls != 1 {
+		t.Errorf("expected %d attempts, got %d", 1, calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 4, 20, 2, 7, 55, 0, time.UTC)
+	cases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Tue, 20 Apr 2021 02:08:55 GMT", time.Minute, true},
+		{"Tue, 20 Apr 2021 02:06:55 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, c := range cases {
+		delay, ok := retryAfter(c.value, now)
+		if delay != c.expected || ok != c.ok {
+			t.Errorf("%q: expected (%v, %v), got (%v, %v)", c.value, c.expected, c.ok, delay, ok)
+		}
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}.withDefaults()
+	for retry := 1; retry <= 64; retry++ {
+		ceiling := 10 * time.Millisecond << uint(retry-1)
+		if retry > 3 || ceiling > policy.MaxBackoff {
+			ceiling = policy.MaxBackoff
+		}
+		for i := 0; i < 20; i++ {
+			if d, ok := policy.backoff(retry, nil); d < 0 || d > ceiling || !ok {
+				t.Fatalf("retry %d: expected backoff in [0, %v], got %v", retry, ceiling, d)
+			}
+		}
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"0"}}}
+	if d, ok := policy.backoff(1, resp); d != 0 || !ok {
+		t.Errorf("expected Retry-After delay %v, got %v", 0, d)
+	}
+	// Retry-After delays longer than the MaxBackoff aren't retried
+	resp = &http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}
+	if _, ok := policy.backoff(1, resp); ok {
+		t.Errorf("expected Retry-After beyond MaxBackoff not to be retried")
+	}
+}
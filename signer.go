@@ -0,0 +1,158 @@
+package sling
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signer signs requests created by a Sling, such as by setting a signature
+// header computed from the method, URL, headers, and body. Signers are called
+// at the end of Request(), after the query, headers, body, and request hooks
+// are applied.
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// SignerFunc is an adapter to allow the use of ordinary functions as
+// Signers.
+type SignerFunc func(req *http.Request) error
+
+// Sign calls f(req).
+func (f SignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// HashBody writes the request Body to w (e.g. a hash.Hash). Bodies which can
+// be re-obtained (see http.Request.GetBody), such as those from a
+// BodyProvider, are read from a copy, so they aren't buffered. Other Bodies,
+// including those streamed from one-shot readers (e.g. FileReader parts or
+// NDJSONChan), are read into memory and replaced, so they can still be sent.
+func HashBody(req *http.Request, w io.Writer) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		_, err = io.Copy(w, body)
+		return err
+	}
+	buf, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(buf))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// HMACSigner is a Signer which signs requests with an HMAC-SHA256 of the
+// request method, URL path, query, signed headers, and body. It sets the
+// headers
+//
+//	X-Timestamp: <unix seconds>
+//	X-Content-Sha256: <hex SHA-256 of the body>
+//	Authorization: HMAC-SHA256 KeyId=<id>, SignedHeaders=<headers>, Signature=<hex HMAC>
+//
+// where the signature is the HMAC of the newline separated string to sign
+//
+//	HMAC-SHA256
+//	<timestamp>
+//	<method>
+//	<escaped path>
+//	<query, sorted by key and value>
+//	<lowercase header>:<values joined by ","> (for each signed header, sorted)
+//	<hex SHA-256 of the body>
+type HMACSigner struct {
+	// KeyID identifies the Key to the server.
+	KeyID string
+	// Key is the shared secret key.
+	Key []byte
+	// SignedHeaders are the request headers included in the signature
+	// (e.g. "Host" or "Content-Type"). Missing headers are signed as empty.
+	SignedHeaders []string
+	// Now returns the signing time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Sign sets the timestamp, body hash, and Authorization headers.
+func (s *HMACSigner) Sign(req *http.Request) error {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	bodyHash := sha256.New()
+	if err := HashBody(req, bodyHash); err != nil {
+		return fmt.Errorf("sling: hashing request body: %w", err)
+	}
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	contentSHA256 := hex.EncodeToString(bodyHash.Sum(nil))
+
+	headers := make([]string, len(s.SignedHeaders))
+	for i, key := range s.SignedHeaders {
+		headers[i] = strings.ToLower(key)
+	}
+	slices.Sort(headers)
+	headers = slices.Compact(headers)
+
+	var b strings.Builder
+	b.WriteString("HMAC-SHA256\n" + timestamp + "\n" + req.Method + "\n" + req.URL.EscapedPath() + "\n")
+	b.WriteString(sortedQuery(req.URL.Query()) + "\n")
+	for _, key := range headers {
+		values := req.Header.Values(key)
+		if key == "host" {
+			values = []string{requestHost(req)}
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.TrimSpace(value)
+		}
+		b.WriteString(key + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+	b.WriteString(contentSHA256)
+
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(b.String()))
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Content-Sha256", contentSHA256)
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC-SHA256 KeyId=%s, SignedHeaders=%s, Signature=%x", s.KeyID, strings.Join(headers, ";"), mac.Sum(nil)))
+	return nil
+}
+
+// requestHost returns the Host header value which will be sent.
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// sortedQuery encodes the query params sorted by key and then value, with
+// all but RFC 3986 unreserved characters percent-encoded.
+func sortedQuery(values url.Values) string {
+	var pairs []string
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		for _, value := range slices.Sorted(slices.Values(values[key])) {
+			pairs = append(pairs, encodeTemplateValue(key, false)+"="+encodeTemplateValue(value, false))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
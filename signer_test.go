@@ -0,0 +1,167 @@
+package sling
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	var signed *http.Request
+	s := New().Get("http://example.com/foo").
+		OnRequest(func(req *http.Request) error {
+			req.Header.Set("X-Hook", "on")
+			return nil
+		}).
+		Signer(SignerFunc(func(req *http.Request) error {
+			// signers see request hook changes
+			if req.Header.Get("X-Hook") != "on" {
+				t.Errorf("expected Signer to be called after request hooks")
+			}
+			signed = req
+			req.Header.Set("Signature", "abc")
+			return nil
+		}))
+	req, err := s.Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if req != signed || req.Header.Get("Signature") != "abc" {
+		t.Errorf("expected request to be signed, got %v", req.Header)
+	}
+
+	// children inherit the Signer, and a nil Signer disables signing
+	if req, _ := s.New().Request(); req.Header.Get("Signature") != "abc" {
+		t.Errorf("expected child Sling requests to be signed")
+	}
+	if req, _ := s.New().Signer(nil).Request(); req.Header.Get("Signature") != "" {
+		t.Errorf("expected request not to be signed, got %v", req.Header)
+	}
+
+	errSign := errors.New("sign")
+	_, err = s.New().Signer(SignerFunc(func(*http.Request) error { return errSign })).Request()
+	if err != errSign {
+		t.Errorf("expected %v, got %v", errSign, err)
+	}
+}
+
+func TestHashBody(t *testing.T) {
+	cases := []struct {
+		sling *Sling
+		body  string
+	}{
+		{New(), ""},
+		// bodies which can be re-obtained
+		{New().BodyJSON(modelA), "{\"text\":\"note\",\"favorite_count\":12}\n"},
+		{New().Body(strings.NewReader("abc")), "abc"},
+		// one-shot bodies are buffered
+		{New().Body(io.MultiReader(strings.NewReader("a"), strings.NewReader("bc"))), "abc"},
+	}
+	for _, c := range cases {
+		req, _ := c.sling.Post("http://example.com/").Request()
+		buf := new(bytes.Buffer)
+		if err := HashBody(req, buf); err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+		if buf.String() != c.body {
+			t.Errorf("expected %q, got %q", c.body, buf.String())
+		}
+		// the body can still be sent
+		if req.Body != nil {
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != c.body {
+				t.Errorf("expected %q, got %q", c.body, body)
+			}
+		}
+	}
+}
+
+func TestHashBody_oneShotProviders(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	content := strings.Repeat("file contents ", 10000)
+	// verify the received Body has its Content-Digest and content
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if expected := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"; r.Header.Get("Content-Digest") != expected {
+			t.Errorf("expected %s, got %s", expected, r.Header.Get("Content-Digest"))
+		}
+		if !strings.Contains(string(body), content) {
+			t.Errorf("expected Body with content, got %d bytes", len(body))
+		}
+	})
+
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		ch <- content
+	}()
+	cases := []*Sling{
+		New().BodyMultipart(FormField("title", "notes"), FileReader("file", "notes.txt", "", strings.NewReader(content))),
+		New().BodyProvider(NDJSONChan(ch)),
+	}
+	for _, s := range cases {
+		_, err := s.Client(client).Post("http://example.com/").ContentDigest(DigestSHA256).ReceiveSuccess(nil)
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+		}
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	key := []byte("secret")
+	// verify signatures as a server would
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodyHash := sha256.Sum256(body)
+		stringToSign := strings.Join([]string{
+			"HMAC-SHA256",
+			r.Header.Get("X-Timestamp"),
+			"POST",
+			"/items",
+			"a=1&b=%20x&b=2",
+			"content-type:application/json",
+			"host:example.com",
+			hex.EncodeToString(bodyHash[:]),
+		}, "\n")
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(stringToSign))
+		expected := fmt.Sprintf("HMAC-SHA256 KeyId=key-1, SignedHeaders=content-type;host, Signature=%x", mac.Sum(nil))
+		if auth := r.Header.Get("Authorization"); auth != expected {
+			t.Errorf("expected %s, got %s", expected, auth)
+		}
+		if r.Header.Get("X-Content-Sha256") != hex.EncodeToString(bodyHash[:]) {
+			t.Errorf("expected X-Content-Sha256 %x, got %s", bodyHash, r.Header.Get("X-Content-Sha256"))
+		}
+		if r.Header.Get("X-Timestamp") != "1700000000" {
+			t.Errorf("expected X-Timestamp %s, got %s", "1700000000", r.Header.Get("X-Timestamp"))
+		}
+	})
+
+	signer := &HMACSigner{
+		KeyID:         "key-1",
+		Key:           key,
+		SignedHeaders: []string{"Host", "Content-Type"},
+		Now:           func() time.Time { return time.Unix(1700000000, 0) },
+	}
+	params := struct {
+		B []string `url:"b"`
+		A int      `url:"a"`
+	}{[]string{"2", " x"}, 1}
+	_, err := New().Client(client).Post("http://example.com/items").QueryStruct(params).BodyJSON(modelA).Signer(signer).ReceiveSuccess(nil)
+	if err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
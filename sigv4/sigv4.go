@@ -0,0 +1,280 @@
+/*
+Package sigv4 signs requests with AWS Signature Version 4, for AWS services
+and S3-compatible object stores.
+
+A Signer signs requests created by a Sling with an Authorization header (see
+Sling.Signer), or presigns them as URLs which can be shared without
+credentials.
+
+	signer := &sigv4.Signer{
+	    Credentials: sigv4.Credentials{AccessKeyID: id, SecretAccessKey: secret},
+	    Region:      "us-east-1",
+	    Service:     "s3",
+	}
+	bucket := sling.New().Base("https://bucket.s3.amazonaws.com/").Signer(signer)
+	resp, err := bucket.New().Put("report.csv").Body(file).ReceiveSuccess(nil)
+
+	req, err := sling.New().Get("https://bucket.s3.amazonaws.com/report.csv").Request()
+	url, err := signer.Presign(req, 15*time.Minute)
+
+Request bodies are hashed by reading a copy from the BodyProvider (see
+sling.HashBody). Set UnsignedPayload to sign streaming bodies without reading
+them, where the service allows it (e.g. S3 over HTTPS).
+*/
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dghubble/sling"
+)
+
+const (
+	algorithm = "AWS4-HMAC-SHA256"
+	// amzDateFormat is the format of X-Amz-Date timestamps
+	amzDateFormat = "20060102T150405Z"
+	// unsignedPayload is the payload hash of requests whose body isn't signed
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// maxPresignExpires is the longest lifetime of a presigned URL
+	maxPresignExpires = 7 * 24 * time.Hour
+)
+
+// ignoredHeaders are not signed, since proxies or the http Transport may
+// change them.
+var ignoredHeaders = map[string]bool{
+	"Authorization":     true,
+	"User-Agent":        true,
+	"X-Amzn-Trace-Id":   true,
+	"Expect":            true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+}
+
+// Credentials are AWS security credentials.
+type Credentials struct {
+	// AccessKeyID is the access key ID.
+	AccessKeyID string
+	// SecretAccessKey is the secret access key.
+	SecretAccessKey string
+	// SessionToken is the session token of temporary credentials, if any.
+	SessionToken string
+}
+
+// Signer is a sling.Signer which signs requests with AWS Signature Version 4.
+type Signer struct {
+	// Credentials are the credentials requests are signed with.
+	Credentials Credentials
+	// Region is the AWS region (e.g. "us-east-1").
+	Region string
+	// Service is the signing name of the service (e.g. "s3"). S3 request
+	// paths are signed as is, rather than normalized and escaped twice, and
+	// include an X-Amz-Content-Sha256 header.
+	Service string
+	// UnsignedPayload signs requests with an "UNSIGNED-PAYLOAD" payload hash,
+	// rather than hashing the body, for streaming bodies or large uploads.
+	UnsignedPayload bool
+	// Now returns the signing time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Sign sets the X-Amz-Date, X-Amz-Security-Token (for temporary
+// credentials), X-Amz-Content-Sha256 (for S3 and unsigned payloads), and
+// Authorization headers of the request. All headers set on the request are
+// signed, except for a few which proxies or the http Transport may change
+// (e.g. User-Agent).
+func (s *Signer) Sign(req *http.Request) error {
+	t := s.now()
+	payloadHash := unsignedPayload
+	if !s.UnsignedPayload {
+		hash := sha256.New()
+		if err := sling.HashBody(req, hash); err != nil {
+			return fmt.Errorf("sigv4: hashing request body: %w", err)
+		}
+		payloadHash = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", t.Format(amzDateFormat))
+	if s.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+	if s.Service == "s3" || s.UnsignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := []string{"host"}
+	for key := range req.Header {
+		if key != "Host" && !ignoredHeaders[key] {
+			headers = append(headers, strings.ToLower(key))
+		}
+	}
+	slices.Sort(headers)
+	signedHeaders := strings.Join(headers, ";")
+
+	signature := s.signature(t, req, req.URL.Query(), headers, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, s.Credentials.AccessKeyID, s.scope(t), signedHeaders, signature))
+	return nil
+}
+
+// Presign returns the request URL with a signature in its query, which
+// authorizes requests with the same method and URL (e.g. GET) for the
+// expiration duration, up to 7 days. Only the Host header is signed. S3 and
+// unsigned payload presigned URLs use an "UNSIGNED-PAYLOAD" payload hash, so
+// the body may be sent by the URL holder.
+func (s *Signer) Presign(req *http.Request, expires time.Duration) (*url.URL, error) {
+	if expires < time.Second || expires > maxPresignExpires {
+		return nil, fmt.Errorf("sigv4: presigned URL expiration must be between 1s and 7 days, got %v", expires)
+	}
+	t := s.now()
+	payloadHash := unsignedPayload
+	if s.Service != "s3" && !s.UnsignedPayload {
+		hash := sha256.New()
+		if err := sling.HashBody(req, hash); err != nil {
+			return nil, fmt.Errorf("sigv4: hashing request body: %w", err)
+		}
+		payloadHash = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", algorithm)
+	query.Set("X-Amz-Credential", s.Credentials.AccessKeyID+"/"+s.scope(t))
+	query.Set("X-Amz-Date", t.Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.Credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.Credentials.SessionToken)
+	}
+	signature := s.signature(t, req, query, []string{"host"}, payloadHash)
+
+	presigned := *req.URL
+	presigned.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return &presigned, nil
+}
+
+// signature returns the hex signature of the request with the query, signed
+// headers (lowercase and sorted), and payload hash.
+func (s *Signer) signature(t time.Time, req *http.Request, query url.Values, headers []string, payloadHash string) string {
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	canonical.WriteString(s.canonicalURI(req.URL) + "\n")
+	canonical.WriteString(canonicalQuery(query) + "\n")
+	for _, key := range headers {
+		canonical.WriteString(key + ":" + canonicalHeaderValue(req, key) + "\n")
+	}
+	canonical.WriteString("\n" + strings.Join(headers, ";") + "\n")
+	canonical.WriteString(payloadHash)
+
+	canonicalHash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := algorithm + "\n" +
+		t.Format(amzDateFormat) + "\n" +
+		s.scope(t) + "\n" +
+		hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.Credentials.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// scope returns the credential scope.
+func (s *Signer) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+func (s *Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// canonicalURI returns the URI-encoded URL path. Paths of services other than
+// S3 are normalized and encoded twice.
+func (s *Signer) canonicalURI(u *url.URL) string {
+	p := u.Path
+	if p == "" {
+		return "/"
+	}
+	if s.Service == "s3" {
+		return escape(p, false)
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return escape(escape(cleaned, false), false)
+}
+
+// canonicalQuery returns the URI-encoded query params, sorted by key and
+// then value.
+func canonicalQuery(query url.Values) string {
+	pairs := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{escape(key, true), escape(value, true)})
+		}
+	}
+	slices.SortFunc(pairs, func(a, b [2]string) int {
+		if c := strings.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		return strings.Compare(a[1], b[1])
+	})
+	encoded := make([]string, len(pairs))
+	for i, pair := range pairs {
+		encoded[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// canonicalHeaderValue returns the header values, trimmed, with sequential
+// spaces collapsed, and joined by commas.
+func canonicalHeaderValue(req *http.Request, key string) string {
+	values := req.Header.Values(key)
+	if key == "host" {
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		values = []string{host}
+	}
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.Join(strings.Fields(value), " ")
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// escape percent-encodes all but RFC 3986 unreserved characters and, unless
+// encodeSlash is true, slashes.
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' && !encodeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
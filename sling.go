@@ -1,10 +1,12 @@
 package sling
 
 import (
+	"context"
 	"encoding/base64"
 	"io"
 	"net/http"
 	"net/url"
+	"reflect"
 
 	goquery "github.com/google/go-querystring/query"
 )
@@ -13,6 +15,7 @@ const (
 	contentType     = "Content-Type"
 	jsonContentType = "application/json"
 	formContentType = "application/x-www-form-urlencoded"
+	xmlContentType  = "application/xml"
 )
 
 // Doer executes http requests.  It is implemented by *http.Client.  You can
@@ -30,14 +33,58 @@ type Sling struct {
 	method string
 	// raw url string for requests
 	rawURL string
+	// paths resolved against the rawURL when requests are created, starting
+	// from the first URI template path
+	paths []string
+	// path template variables
+	pathParams []interface{}
 	// stores key-values pairs to add to request's Headers
 	header http.Header
-	// url tagged query structs
+	// error encoding a header struct, returned when requests are created
+	headerErr error
+	// url tagged query structs, query params, and QueryEncoders
 	queryStructs []interface{}
+	// format of query params with multiple values
+	arrayFormat ArrayFormat
 	// body provider
 	bodyProvider BodyProvider
+	// Content-Digest algorithm for request bodies, if set
+	contentDigest string
+	// compression of request bodies, nil to send bodies as-is
+	compression *bodyCompression
 	// response decoder
 	responseDecoder ResponseDecoder
+	// verify response Bodies against their digest headers
+	verifyDigest bool
+	// maximum ratio of decompressed to compressed response Body sizes, 0 for
+	// the default
+	maxRatio int
+	// maximum response Body bytes read, 0 for no limit
+	maxBodyBytes int64
+	// maximum response Body bytes drained for connection reuse, negative to
+	// drain the whole Body
+	maxDrainBytes int64
+	// context for requests
+	ctx context.Context
+	// retry policy, nil to send requests once
+	retryPolicy *RetryPolicy
+	// source of Authorization header tokens
+	tokenSource *reuseTokenSource
+	// Digest credentials and challenge
+	digestAuth *digestAuth
+	// return HTTPErrors for non-2XX responses
+	errorOnFailure bool
+	// errors wrapped by HTTPErrors for response status codes
+	statusErrors map[int]error
+	// middleware wrapping the http Client, outermost first
+	middleware []Middleware
+	// hooks called with created requests and received responses
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+	// signs created requests, if set
+	signer Signer
+	// setters modify a copy, rather than the Sling
+	immutable bool
 }
 
 // New returns a new Sling with an http DefaultClient.
@@ -48,6 +95,7 @@ func New() *Sling {
 		header:          make(http.Header),
 		queryStructs:    make([]interface{}, 0),
 		responseDecoder: jsonDecoder{},
+		maxDrainBytes:   -1,
 	}
 }
 
@@ -67,17 +115,71 @@ func (s *Sling) New() *Sling {
 	// copy Headers pairs into new Header map
 	headerCopy := make(http.Header)
 	for k, v := range s.header {
-		headerCopy[k] = v
+		headerCopy[k] = append([]string(nil), v...)
+	}
+	var statusErrorsCopy map[int]error
+	if s.statusErrors != nil {
+		statusErrorsCopy = make(map[int]error, len(s.statusErrors))
+		for code, err := range s.statusErrors {
+			statusErrorsCopy[code] = err
+		}
 	}
 	return &Sling{
 		httpClient:      s.httpClient,
 		method:          s.method,
 		rawURL:          s.rawURL,
+		paths:           append([]string(nil), s.paths...),
+		pathParams:      append([]interface{}(nil), s.pathParams...),
 		header:          headerCopy,
+		headerErr:       s.headerErr,
 		queryStructs:    append([]interface{}{}, s.queryStructs...),
+		arrayFormat:     s.arrayFormat,
 		bodyProvider:    s.bodyProvider,
+		contentDigest:   s.contentDigest,
+		compression:     s.compression,
 		responseDecoder: s.responseDecoder,
+		verifyDigest:    s.verifyDigest,
+		maxRatio:        s.maxRatio,
+		maxBodyBytes:    s.maxBodyBytes,
+		maxDrainBytes:   s.maxDrainBytes,
+		ctx:             s.ctx,
+		retryPolicy:     s.retryPolicy,
+		tokenSource:     s.tokenSource,
+		digestAuth:      s.digestAuth,
+		errorOnFailure:  s.errorOnFailure,
+		statusErrors:    statusErrorsCopy,
+		middleware:      append([]Middleware(nil), s.middleware...),
+		requestHooks:    append([]RequestHook(nil), s.requestHooks...),
+		responseHooks:   append([]ResponseHook(nil), s.responseHooks...),
+		signer:          s.signer,
+		immutable:       s.immutable,
+	}
+}
+
+// Immutable returns a copy of the Sling whose setters return modified copies,
+// rather than modifying the Sling. For example,
+//
+//	base := sling.New().Base("https://api.io/").Immutable()
+//	fooSling := base.Get("foo/")
+//	barSling := base.Get("bar/")
+//
+// fooSling and barSling are created without calling New and base is left
+// unmodified, so an immutable Sling can be shared between goroutines.
+// Children of an immutable Sling are immutable. Pointer query structs are
+// copied when they are set (see QueryStruct).
+func (s *Sling) Immutable() *Sling {
+	s = s.New()
+	s.immutable = true
+	return s
+}
+
+// builder returns the Sling to modify in a setter, a copy of the Sling if it
+// is immutable.
+func (s *Sling) builder() *Sling {
+	if s.immutable {
+		return s.New()
 	}
+	return s
 }
 
 // Http Client
@@ -94,6 +196,7 @@ func (s *Sling) Client(httpClient *http.Client) *Sling {
 // Doer sets the custom Doer implementation used to do requests.
 // If a nil client is given, the http.DefaultClient will be used.
 func (s *Sling) Doer(doer Doer) *Sling {
+	s = s.builder()
 	if doer == nil {
 		s.httpClient = http.DefaultClient
 	} else {
@@ -102,60 +205,144 @@ func (s *Sling) Doer(doer Doer) *Sling {
 	return s
 }
 
+// Retry sets the RetryPolicy used to retry failed requests sent by the Sling
+// (see NewRetryDoer). If a nil policy is given, requests are sent once.
+func (s *Sling) Retry(policy *RetryPolicy) *Sling {
+	s = s.builder()
+	s.retryPolicy = policy
+	return s
+}
+
+// Use appends middleware which wraps the http Client used to send requests.
+// Middleware is applied in order, so the first middleware sees requests
+// first. Middleware is inherited by children (see New()) and applies to each
+// retry attempt (see Retry).
+func (s *Sling) Use(middleware ...Middleware) *Sling {
+	s = s.builder()
+	s.middleware = append(s.middleware, middleware...)
+	return s
+}
+
+// OnRequest appends a hook which is called with each request created by the
+// Sling (see Request()).
+func (s *Sling) OnRequest(hook RequestHook) *Sling {
+	s = s.builder()
+	if hook != nil {
+		s.requestHooks = append(s.requestHooks, hook)
+	}
+	return s
+}
+
+// OnResponse appends a hook which is called with each response received by
+// the Sling, before the response is decoded.
+func (s *Sling) OnResponse(hook ResponseHook) *Sling {
+	s = s.builder()
+	if hook != nil {
+		s.responseHooks = append(s.responseHooks, hook)
+	}
+	return s
+}
+
+// Signer sets the Signer which signs each request created by the Sling (see
+// Request()), after its URL, headers, and body are set. Headers set by
+// Middleware are added after requests are signed. If a nil Signer is given,
+// requests are not signed.
+func (s *Sling) Signer(signer Signer) *Sling {
+	s = s.builder()
+	s.signer = signer
+	return s
+}
+
+// doer returns the Doer used to send requests. If the last attempt of a
+// retried request receives a response, the errors of every attempt are
+// recorded in attempts.
+func (s *Sling) doer(attempts *RetryError) Doer {
+	doer := Chain(s.httpClient, s.middleware...)
+	if s.tokenSource != nil {
+		doer = &tokenDoer{next: doer, src: s.tokenSource}
+	}
+	if s.digestAuth != nil {
+		doer = &digestDoer{next: doer, auth: s.digestAuth}
+	}
+	if s.retryPolicy != nil {
+		doer = &retryDoer{next: doer, policy: s.retryPolicy.withDefaults(), attempts: attempts}
+	}
+	return doer
+}
+
+// Context
+
+// Context sets the context used for requests created by the Sling and its
+// children (see New()). Cancelling the context aborts body encoding, sending
+// the request, and decoding the response. If a nil context is given,
+// context.Background() will be used.
+func (s *Sling) Context(ctx context.Context) *Sling {
+	s = s.builder()
+	s.ctx = ctx
+	return s
+}
+
+// context returns the Sling's context or context.Background().
+func (s *Sling) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
 // Method
 
 // Head sets the Sling method to HEAD and sets the given pathURL.
 func (s *Sling) Head(pathURL string) *Sling {
-	s.method = "HEAD"
-	return s.Path(pathURL)
+	return s.methodPath("HEAD", pathURL)
 }
 
 // Get sets the Sling method to GET and sets the given pathURL.
 func (s *Sling) Get(pathURL string) *Sling {
-	s.method = "GET"
-	return s.Path(pathURL)
+	return s.methodPath("GET", pathURL)
 }
 
 // Post sets the Sling method to POST and sets the given pathURL.
 func (s *Sling) Post(pathURL string) *Sling {
-	s.method = "POST"
-	return s.Path(pathURL)
+	return s.methodPath("POST", pathURL)
 }
 
 // Put sets the Sling method to PUT and sets the given pathURL.
 func (s *Sling) Put(pathURL string) *Sling {
-	s.method = "PUT"
-	return s.Path(pathURL)
+	return s.methodPath("PUT", pathURL)
 }
 
 // Patch sets the Sling method to PATCH and sets the given pathURL.
 func (s *Sling) Patch(pathURL string) *Sling {
-	s.method = "PATCH"
-	return s.Path(pathURL)
+	return s.methodPath("PATCH", pathURL)
 }
 
 // Delete sets the Sling method to DELETE and sets the given pathURL.
 func (s *Sling) Delete(pathURL string) *Sling {
-	s.method = "DELETE"
-	return s.Path(pathURL)
+	return s.methodPath("DELETE", pathURL)
 }
 
 // Options sets the Sling method to OPTIONS and sets the given pathURL.
 func (s *Sling) Options(pathURL string) *Sling {
-	s.method = "OPTIONS"
-	return s.Path(pathURL)
+	return s.methodPath("OPTIONS", pathURL)
 }
 
 // Trace sets the Sling method to TRACE and sets the given pathURL.
 func (s *Sling) Trace(pathURL string) *Sling {
-	s.method = "TRACE"
-	return s.Path(pathURL)
+	return s.methodPath("TRACE", pathURL)
 }
 
 // Connect sets the Sling method to CONNECT and sets the given pathURL.
 func (s *Sling) Connect(pathURL string) *Sling {
-	s.method = "CONNECT"
-	return s.Path(pathURL)
+	return s.methodPath("CONNECT", pathURL)
+}
+
+// methodPath sets the Sling method and sets the given pathURL.
+func (s *Sling) methodPath(method, pathURL string) *Sling {
+	s = s.builder()
+	s.method = method
+	s.resolvePath(pathURL)
+	return s
 }
 
 // Header
@@ -163,6 +350,7 @@ func (s *Sling) Connect(pathURL string) *Sling {
 // Add adds the key, value pair in Headers, appending values for existing keys
 // to the key's values. Header keys are canonicalized.
 func (s *Sling) Add(key, value string) *Sling {
+	s = s.builder()
 	s.header.Add(key, value)
 	return s
 }
@@ -170,10 +358,49 @@ func (s *Sling) Add(key, value string) *Sling {
 // Set sets the key, value pair in Headers, replacing existing values
 // associated with key. Header keys are canonicalized.
 func (s *Sling) Set(key, value string) *Sling {
+	s = s.builder()
 	s.header.Set(key, value)
 	return s
 }
 
+// Del deletes the values associated with key from Headers, including values
+// set by parent Slings. Header keys are canonicalized.
+func (s *Sling) Del(key string) *Sling {
+	s = s.builder()
+	s.header.Del(key)
+	return s
+}
+
+// HeaderStruct encodes the fields of the header tagged struct as Headers,
+// replacing existing values associated with their keys. Fields are tagged
+// with the header key and options (e.g. `header:"X-Request-Id,omitempty"`).
+// Strings, numbers, bools, times (HTTP-dates, or Unix seconds with the "unix"
+// option), durations (seconds), and encoding.TextMarshalers are supported.
+// Slices are encoded as multiple values, or a comma-separated value with the
+// "comma" option. Untagged fields use the field name as the key and fields
+// tagged "-" are skipped.
+//
+// Unlike QueryStruct, the struct is encoded when HeaderStruct is called, so
+// later calls to Set, Add, or Del apply in order. Any encoding error is
+// returned when requests are created (see Request()).
+func (s *Sling) HeaderStruct(headerStruct interface{}) *Sling {
+	if headerStruct == nil {
+		return s
+	}
+	s = s.builder()
+	header, err := encodeHeaders(headerStruct)
+	if err != nil {
+		if s.headerErr == nil {
+			s.headerErr = err
+		}
+		return s
+	}
+	for key, values := range header {
+		s.header[key] = values
+	}
+	return s
+}
+
 // SetBasicAuth sets the Authorization header to use HTTP Basic Authentication
 // with the provided username and password. With HTTP Basic Authentication
 // the provided username and password are not encrypted.
@@ -181,6 +408,41 @@ func (s *Sling) SetBasicAuth(username, password string) *Sling {
 	return s.Set("Authorization", "Basic "+basicAuth(username, password))
 }
 
+// BearerAuth sets the Authorization header to use the Bearer token.
+func (s *Sling) BearerAuth(token string) *Sling {
+	return s.Set("Authorization", "Bearer "+token)
+}
+
+// TokenSource sets the TokenSource of tokens sent in the Authorization header
+// of each request. Tokens are cached and shared with children (see New()),
+// and refreshed 30 seconds before they expire (see ReuseTokenSource). If a
+// request receives a 401 Unauthorized response, the token is refreshed once,
+// even across concurrent requests, and the request is sent again. If a nil
+// TokenSource is given, tokens are not sent.
+func (s *Sling) TokenSource(src TokenSource) *Sling {
+	s = s.builder()
+	if src == nil {
+		s.tokenSource = nil
+		return s
+	}
+	s.tokenSource = ReuseTokenSource(src, defaultTokenRefreshEarly).(*reuseTokenSource)
+	return s
+}
+
+// DigestAuth sets the username and password used to authorize requests
+// with HTTP Digest Authentication (RFC 7616). When a request receives a 401
+// Unauthorized response with a Digest challenge, the request is sent again
+// with a response to the challenge. MD5, SHA-256, and their "-sess"
+// variants are supported, with qop "auth" (preferred) or "auth-int", which
+// hashes the body. The challenge is cached and shared with children (see
+// New()), so later requests are authorized without a round trip, with an
+// incrementing nonce count.
+func (s *Sling) DigestAuth(username, password string) *Sling {
+	s = s.builder()
+	s.digestAuth = newDigestAuth(username, password)
+	return s
+}
+
 // basicAuth returns the base64 encoded username:password for basic auth copied
 // from net/http.
 func basicAuth(username, password string) string {
@@ -193,20 +455,56 @@ func basicAuth(username, password string) string {
 // Base sets the rawURL. If you intend to extend the url with Path,
 // baseUrl should be specified with a trailing slash.
 func (s *Sling) Base(rawURL string) *Sling {
+	s = s.builder()
 	s.rawURL = rawURL
+	s.paths = nil
 	return s
 }
 
 // Path extends the rawURL with the given path by resolving the reference to
 // an absolute URL. If parsing errors occur, the rawURL is left unmodified.
+//
+// The path may be an RFC 6570 URI template (e.g.
+// "repos/{owner}/{repo}/issues{?state,labels}"), which is expanded with the
+// variables set by PathParams when requests are created (see Request()).
+// Template paths, and any paths after them, are resolved against the rawURL
+// after expansion.
 func (s *Sling) Path(path string) *Sling {
+	s = s.builder()
+	s.resolvePath(path)
+	return s
+}
+
+// PathParams appends path template variables from a map with string keys
+// (e.g. map[string]string or map[string]interface{}) or a struct with fields
+// tagged with "path" (e.g. `path:"owner"`). Later variables take precedence.
+// String, number, and other values are expanded as strings, slices as lists,
+// and maps as associative arrays. Immutable Slings copy the struct pointed to
+// by a pointer params value.
+func (s *Sling) PathParams(params interface{}) *Sling {
+	if params == nil {
+		return s
+	}
+	s = s.builder()
+	if s.immutable {
+		params = snapshot(params)
+	}
+	s.pathParams = append(s.pathParams, params)
+	return s
+}
+
+// resolvePath extends the rawURL with the given path, or appends the path to
+// the paths resolved when requests are created if it is a URI template.
+func (s *Sling) resolvePath(path string) {
+	if len(s.paths) > 0 || isTemplate(path) {
+		s.paths = append(s.paths, path)
+		return
+	}
 	baseURL, baseErr := url.Parse(s.rawURL)
 	pathURL, pathErr := url.Parse(path)
 	if baseErr == nil && pathErr == nil {
 		s.rawURL = baseURL.ResolveReference(pathURL).String()
-		return s
 	}
-	return s
 }
 
 // QueryStruct appends the queryStruct to the Sling's queryStructs. The value
@@ -214,13 +512,73 @@ func (s *Sling) Path(path string) *Sling {
 // new requests (see Request()).
 // The queryStruct argument should be a pointer to a url tagged struct. See
 // https://godoc.org/github.com/google/go-querystring/query for details.
+// The queryStruct may also be a url.Values, map[string]string, or a
+// QueryEncoder.
+// Immutable Slings copy the struct pointed to by a pointer queryStruct, so
+// later changes to it are not encoded.
 func (s *Sling) QueryStruct(queryStruct interface{}) *Sling {
-	if queryStruct != nil {
-		s.queryStructs = append(s.queryStructs, queryStruct)
+	if queryStruct == nil {
+		return s
 	}
+	s = s.builder()
+	if s.immutable {
+		queryStruct = snapshot(queryStruct)
+	}
+	s.queryStructs = append(s.queryStructs, queryStruct)
+	return s
+}
+
+// QueryParam adds the key, value pair to the url query parameters of new
+// requests (see Request()), appending to existing values for the key.
+func (s *Sling) QueryParam(key, value string) *Sling {
+	return s.QueryStruct(addQuery{key: {value}})
+}
+
+// SetQueryParam sets the key, value pair in the url query parameters of new
+// requests (see Request()), replacing existing values for the key, including
+// those set by parent Slings or the base URL.
+func (s *Sling) SetQueryParam(key, value string) *Sling {
+	return s.QueryStruct(setQuery{key: {value}})
+}
+
+// DelQueryParam deletes the values for the key from the url query
+// parameters of new requests (see Request()), including those set by parent
+// Slings or the base URL.
+func (s *Sling) DelQueryParam(key string) *Sling {
+	return s.QueryStruct(delQuery{key})
+}
+
+// QueryValues adds a copy of the values to the url query parameters of new
+// requests (see Request()), appending to existing values for their keys.
+func (s *Sling) QueryValues(values url.Values) *Sling {
+	if values == nil {
+		return s
+	}
+	return s.QueryStruct(addQuery(queryValues(values)))
+}
+
+// QueryArrayFormat sets the format of url query parameters with multiple
+// values (default ArrayRepeat). Parameters with a single value are sent as
+// scalars (see ArrayFormat).
+func (s *Sling) QueryArrayFormat(format ArrayFormat) *Sling {
+	s = s.builder()
+	s.arrayFormat = format
 	return s
 }
 
+// snapshot returns a copy of the struct pointed to by v, or v if it is not a
+// pointer to a struct.
+func snapshot(v interface{}) interface{} {
+	if _, ok := v.(QueryEncoder); ok {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return v
+	}
+	return rv.Elem().Interface()
+}
+
 // Body
 
 // Body sets the Sling's body. The body value will be set as the Body on new
@@ -239,11 +597,12 @@ func (s *Sling) BodyProvider(body BodyProvider) *Sling {
 	if body == nil {
 		return s
 	}
+	s = s.builder()
 	s.bodyProvider = body
 
 	ct := body.ContentType()
 	if ct != "" {
-		s.Set(contentType, ct)
+		s.header.Set(contentType, ct)
 	}
 
 	return s
@@ -260,9 +619,21 @@ func (s *Sling) BodyJSON(bodyJSON interface{}) *Sling {
 	return s.BodyProvider(jsonBodyProvider{payload: bodyJSON})
 }
 
+// BodyXML sets the Sling's bodyXML. The value pointed to by the bodyXML
+// will be XML encoded as the Body on new requests (see Request()).
+// The bodyXML argument should be a pointer to an XML tagged struct. See
+// https://golang.org/pkg/encoding/xml/#Marshal for details.
+func (s *Sling) BodyXML(bodyXML interface{}) *Sling {
+	if bodyXML == nil {
+		return s
+	}
+	return s.BodyProvider(xmlBodyProvider{payload: bodyXML})
+}
+
 // BodyForm sets the Sling's bodyForm. The value pointed to by the bodyForm
 // will be url encoded as the Body on new requests (see Request()).
-// The bodyForm argument should be a pointer to a url tagged struct. See
+// The bodyForm argument should be a pointer to a url tagged struct or a
+// url.Values. See
 // https://godoc.org/github.com/google/go-querystring/query for details.
 func (s *Sling) BodyForm(bodyForm interface{}) *Sling {
 	if bodyForm == nil {
@@ -271,48 +642,193 @@ func (s *Sling) BodyForm(bodyForm interface{}) *Sling {
 	return s.BodyProvider(formBodyProvider{payload: bodyForm})
 }
 
+// BodyMultipart sets the Sling's body to a multipart/form-data encoding of
+// the given parts (see FormField, FormStruct, FileReader, and FilePath).
+// Parts are streamed as the Body on new requests (see Request()), so large
+// files aren't buffered in memory. Requests with FileReader parts can't be
+// sent again, so they aren't retried or redirected. Requests will include a
+// "multipart/form-data" Content-Type header with the part boundary.
+func (s *Sling) BodyMultipart(parts ...Part) *Sling {
+	return s.BodyProvider(newMultipartBodyProvider(parts))
+}
+
+// ContentDigest sets the Sling to set the Content-Digest header (RFC 9530) of
+// request Bodies, using the DigestSHA256 or DigestSHA512 algorithm. Bodies
+// from a BodyProvider are digested from a copy, or buffered if they can only
+// be read once (see HashBody), before the request is signed. An empty
+// algorithm disables Content-Digest.
+func (s *Sling) ContentDigest(algorithm string) *Sling {
+	s = s.builder()
+	s.contentDigest = algorithm
+	return s
+}
+
+// CompressBody sets the Sling to compress request Bodies of at least minSize
+// bytes with the EncodingGzip or EncodingDeflate Content-Encoding. Bodies
+// from any BodyProvider are compressed as they're sent, rather than buffered,
+// so compressed requests have an unknown Content-Length. Smaller Bodies are
+// sent uncompressed. Bodies of unknown length (e.g. from BodyMultipart or
+// NDJSONChan) are always compressed, since their size isn't known until
+// they're sent. An empty encoding disables compression.
+func (s *Sling) CompressBody(encoding string, minSize int) *Sling {
+	s = s.builder()
+	s.compression = nil
+	if encoding != "" {
+		s.compression = &bodyCompression{encoding: encoding, minSize: max(minSize, 0)}
+	}
+	return s
+}
+
 // Requests
 
 // Request returns a new http.Request created with the Sling properties.
 // Returns any errors parsing the rawURL, encoding query structs, encoding
 // the body, or creating the http.Request.
 func (s *Sling) Request() (*http.Request, error) {
-	reqURL, err := url.Parse(s.rawURL)
+	return s.RequestContext(s.context())
+}
+
+// RequestContext returns a new http.Request created with the Sling properties
+// and the given context, which overrides any context set with Context.
+func (s *Sling) RequestContext(ctx context.Context) (*http.Request, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if s.headerErr != nil {
+		return nil, s.headerErr
+	}
+	reqURL, err := s.expandURL()
 	if err != nil {
 		return nil, err
 	}
 
-	err = addQueryStructs(reqURL, s.queryStructs)
+	err = addQueryStructs(reqURL, s.queryStructs, s.arrayFormat)
 	if err != nil {
 		return nil, err
 	}
 
 	var body io.Reader
 	if s.bodyProvider != nil {
-		body, err = s.bodyProvider.Body()
+		body, err = provideBody(ctx, s.bodyProvider)
 		if err != nil {
 			return nil, err
 		}
 	}
-	req, err := http.NewRequest(s.method, reqURL.String(), body)
+	req, err := http.NewRequestWithContext(ctx, s.method, reqURL.String(), body)
 	if err != nil {
 		return nil, err
 	}
+	if s.bodyProvider != nil && replayable(s.bodyProvider) {
+		// re-obtain the body from the provider for retries and redirects
+		provider := s.bodyProvider
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := provideBody(ctx, provider)
+			if err != nil {
+				return nil, err
+			}
+			if rc, ok := body.(io.ReadCloser); ok {
+				return rc, nil
+			}
+			return io.NopCloser(body), nil
+		}
+	}
+	if s.compression != nil && req.Body != nil && req.Body != http.NoBody {
+		if err := compressRequest(req, s.compression); err != nil {
+			return nil, err
+		}
+	}
 	addHeaders(req, s.header)
+	if accepter, ok := s.responseDecoder.(interface{ Accept() string }); ok && req.Header.Get("Accept") == "" {
+		if accept := accepter.Accept(); accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+	}
+	for _, hook := range s.requestHooks {
+		if err := hook(req); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	if s.contentDigest != "" && req.Body != nil {
+		if err := SetContentDigest(req, s.contentDigest); err != nil {
+			req.Body.Close()
+			return nil, err
+		}
+	}
+	if s.signer != nil {
+		if err := s.signer.Sign(req); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+	}
 	return req, err
 }
 
+// expandURL returns the rawURL extended with the Sling's paths, with URI
+// templates expanded with the path params.
+func (s *Sling) expandURL() (*url.URL, error) {
+	reqURL, err := url.Parse(s.rawURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range s.paths {
+		path, err = expandTemplate(path, s.pathParams)
+		if err != nil {
+			return nil, err
+		}
+		pathURL, err := url.Parse(path)
+		if err != nil {
+			return nil, err
+		}
+		reqURL = reqURL.ResolveReference(pathURL)
+	}
+	return reqURL, nil
+}
+
 // addQueryStructs parses url tagged query structs using go-querystring to
-// encode them to url.Values and format them onto the url.RawQuery. Any
-// query parsing or encoding errors are returned.
-func addQueryStructs(reqURL *url.URL, queryStructs []interface{}) error {
+// encode them to url.Values and format them onto the url.RawQuery. Query
+// params, url.Values, and QueryEncoders are merged in the order they were
+// set. Any query parsing or encoding errors are returned.
+func addQueryStructs(reqURL *url.URL, queryStructs []interface{}, format ArrayFormat) error {
 	urlValues, err := url.ParseQuery(reqURL.RawQuery)
 	if err != nil {
 		return err
 	}
 	// encodes query structs into a url.Values map and merges maps
 	for _, queryStruct := range queryStructs {
-		queryValues, err := goquery.Values(queryStruct)
+		var queryValues url.Values
+		switch v := queryStruct.(type) {
+		case setQuery:
+			// replace values, e.g. for pagination parameters
+			for key, values := range v {
+				urlValues[key] = values
+			}
+			continue
+		case delQuery:
+			for _, key := range v {
+				urlValues.Del(key)
+			}
+			continue
+		case addQuery:
+			queryValues = url.Values(v)
+		case url.Values:
+			queryValues = v
+		case map[string][]string:
+			queryValues = v
+		case map[string]string:
+			queryValues = make(url.Values, len(v))
+			for key, value := range v {
+				queryValues.Set(key, value)
+			}
+		case QueryEncoder:
+			queryValues, err = v.EncodeQuery()
+		default:
+			queryValues, err = goquery.Values(queryStruct)
+		}
 		if err != nil {
 			return err
 		}
@@ -323,7 +839,7 @@ func addQueryStructs(reqURL *url.URL, queryStructs []interface{}) error {
 		}
 	}
 	// url.Values format to a sorted "url encoded" string, e.g. "key=val&foo=bar"
-	reqURL.RawQuery = urlValues.Encode()
+	reqURL.RawQuery = formatArrays(urlValues, format).Encode()
 	return nil
 }
 
@@ -344,10 +860,103 @@ func (s *Sling) ResponseDecoder(decoder ResponseDecoder) *Sling {
 	if decoder == nil {
 		return s
 	}
+	s = s.builder()
 	s.responseDecoder = decoder
 	return s
 }
 
+// ErrorOnFailure sets the Sling to return an *HTTPError for non-2XX
+// responses. The HTTPError holds the decoded failureV value, if one was
+// provided, and wraps any error mapped to the status code with
+// MapStatusError.
+func (s *Sling) ErrorOnFailure() *Sling {
+	s = s.builder()
+	s.errorOnFailure = true
+	return s
+}
+
+// MapStatusError maps a response status code to an error wrapped by the
+// HTTPErrors returned for responses with that status code, so callers may
+// check for their own sentinel errors with errors.Is. For example,
+//
+//	var ErrNotFound = errors.New("not found")
+//	base := sling.New().ErrorOnFailure().MapStatusError(404, ErrNotFound)
+//	_, err := base.New().Get("missing").ReceiveSuccess(v)
+//	errors.Is(err, ErrNotFound) // true
+func (s *Sling) MapStatusError(code int, err error) *Sling {
+	s = s.builder()
+	if s.statusErrors == nil {
+		s.statusErrors = make(map[int]error)
+	}
+	s.statusErrors[code] = err
+	return s
+}
+
+// RegisterMediaType registers the ResponseDecoder for responses with the
+// media type. The Sling's ResponseDecoder is replaced with a copy of its
+// NegotiatingDecoder with the media type registered, or a new
+// NegotiatingDecoder if the Sling's ResponseDecoder isn't one.
+func (s *Sling) RegisterMediaType(mediaType string, decoder ResponseDecoder) *Sling {
+	s = s.builder()
+	negotiator, ok := s.responseDecoder.(*NegotiatingDecoder)
+	if ok {
+		negotiator = negotiator.clone()
+	} else {
+		negotiator = NewNegotiatingDecoder()
+	}
+	s.responseDecoder = negotiator.Register(mediaType, decoder)
+	return s
+}
+
+// MaxDecompressionRatio sets the maximum ratio of the decompressed size of
+// a response Body to its compressed size, above which reading the Body
+// returns a *DecompressionRatioError, to protect against decompression bombs.
+// Receive, Do, and streamed receives (e.g. DoStream) decompress gzip and
+// deflate encoded responses which the Transport doesn't (e.g. deflate, or
+// gzip when the Accept-Encoding header is set explicitly) before decoding
+// them. Bodies smaller than 1MiB aren't limited. A ratio of 0 sets the
+// DefaultMaxDecompressionRatio and a negative ratio disables the limit.
+func (s *Sling) MaxDecompressionRatio(ratio int) *Sling {
+	s = s.builder()
+	s.maxRatio = ratio
+	return s
+}
+
+// MaxResponseBytes sets the maximum number of response Body bytes read when
+// decoding responses, including failure responses, streamed responses (see
+// ReceiveStream), and pages (see Paginate). Reading more returns a
+// *ResponseTooLargeError. The limit applies to decompressed Bodies. A limit
+// of 0 removes the limit (the default).
+func (s *Sling) MaxResponseBytes(n int64) *Sling {
+	s = s.builder()
+	s.maxBodyBytes = max(n, 0)
+	return s
+}
+
+// MaxDrainBytes sets the maximum number of bytes read from the remainder of
+// a response Body after decoding, so the http Transport can reuse the
+// connection. Bodies with more remaining are closed without being read
+// further, which closes the connection. A negative limit drains the whole
+// Body (the default).
+func (s *Sling) MaxDrainBytes(n int64) *Sling {
+	s = s.builder()
+	s.maxDrainBytes = n
+	return s
+}
+
+// VerifyDigest sets the Sling to verify response Bodies against their
+// Content-Digest or Repr-Digest (RFC 9530) header, or a legacy Content-MD5
+// header, as they're read. A Body which doesn't match returns a
+// *DigestMismatchError in place of io.EOF, so verification works while
+// streaming (e.g. ReceiveStream) without buffering the Body. Receive and Do
+// read the remainder of decoded Bodies to verify them. Responses without
+// a digest header of a supported algorithm are not verified.
+func (s *Sling) VerifyDigest() *Sling {
+	s = s.builder()
+	s.verifyDigest = true
+	return s
+}
+
 // ReceiveSuccess creates a new HTTP request and returns the response. Success
 // responses (2XX) are JSON decoded into the value pointed to by successV.
 // Any error creating the request, sending it, or decoding a 2XX response
@@ -371,14 +980,26 @@ func (s *Sling) Receive(successV, failureV interface{}) (*http.Response, error)
 	return s.Do(req, successV, failureV)
 }
 
+// ReceiveContext is like Receive, but creates the request with the given
+// context, which overrides any context set with Context.
+func (s *Sling) ReceiveContext(ctx context.Context, successV, failureV interface{}) (*http.Response, error) {
+	req, err := s.RequestContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Do(req, successV, failureV)
+}
+
 // Do sends an HTTP request and returns the response. Success responses (2XX)
 // are JSON decoded into the value pointed to by successV and other responses
 // are JSON decoded into the value pointed to by failureV.
 // If the status code of response is 204(no content) or the Content-Length is 0,
 // decoding is skipped. Any error sending the request or decoding the response
-// is returned.
+// is returned. With ErrorOnFailure set, non-2XX responses return an
+// *HTTPError. If the last attempt of a retried request receives a response,
+// it is decoded and a *RetryError reporting every attempt is returned.
 func (s *Sling) Do(req *http.Request, successV, failureV interface{}) (*http.Response, error) {
-	resp, err := s.httpClient.Do(req)
+	resp, attempts, err := s.send(req)
 	if err != nil {
 		return resp, err
 	}
@@ -389,18 +1010,102 @@ func (s *Sling) Do(req *http.Request, successV, failureV interface{}) (*http.Res
 	// reuse HTTP/1.x "keep-alive" TCP connections if the Body is
 	// not read to completion and closed.
 	// See: https://golang.org/pkg/net/http/#Response
-	defer io.Copy(io.Discard, resp.Body)
+	defer s.drain(resp.Body)
+
+	verifying := s.wrapResponseBody(req, resp)
+	s.decompressBody(req, resp)
+	s.limitBody(resp)
+	return resp, withAttempts(attempts, s.decode(req, resp, verifying, successV, failureV))
+}
+
+// decode decodes the response Body into successV or failureV for Do.
+func (s *Sling) decode(req *http.Request, resp *http.Response, verifying bool, successV, failureV interface{}) error {
+	if s.errorOnFailure && !isSuccess(resp.StatusCode) {
+		return newHTTPError(req, resp, s.responseDecoder, failureV, s.statusErrors)
+	}
 
 	// Don't try to decode on 204s or Content-Length is 0
 	if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
-		return resp, nil
+		return verifyBody(resp, verifying)
 	}
 
 	// Decode from json
 	if successV != nil || failureV != nil {
-		err = decodeResponse(resp, s.responseDecoder, successV, failureV)
+		if err := decodeResponse(resp, s.responseDecoder, successV, failureV); err != nil {
+			return err
+		}
+	}
+	return verifyBody(resp, verifying)
+}
+
+// send sends the request with the Sling's Doer and calls response hooks. If
+// the last attempt of a retried request received a response, the response is
+// returned with the *RetryError reporting every attempt.
+func (s *Sling) send(req *http.Request) (*http.Response, *RetryError, error) {
+	attempts := new(RetryError)
+	resp, err := s.doer(attempts).Do(req)
+	if err != nil {
+		return resp, nil, err
 	}
-	return resp, err
+	if len(attempts.Errors) == 0 {
+		attempts = nil
+	}
+	for _, hook := range s.responseHooks {
+		if err := hook(resp); err != nil {
+			resp.Body.Close()
+			return nil, nil, err
+		}
+	}
+	return resp, attempts, nil
+}
+
+// wrapResponseBody wraps the response Body for decoding and returns whether
+// its digest is verified as it's read, because the Sling verifies digests or
+// a response hook wrapped it with VerifyContentDigest (e.g. an httpsig
+// Verifier).
+func (s *Sling) wrapResponseBody(req *http.Request, resp *http.Response) bool {
+	_, hooked := resp.Body.(*digestReadCloser)
+	resp.Body = &contextReadCloser{ctx: req.Context(), rc: resp.Body}
+	if s.verifyDigest {
+		verifyDigest(req, resp)
+	}
+	return s.verifyDigest || hooked
+}
+
+// decompressBody decompresses an encoded response Body for decoding (see
+// MaxDecompressionRatio) and verifies its Repr-Digest, if the Sling verifies
+// digests.
+func (s *Sling) decompressBody(req *http.Request, resp *http.Response) {
+	maxRatio := s.maxRatio
+	if maxRatio == 0 {
+		maxRatio = DefaultMaxDecompressionRatio
+	}
+	if decompressResponse(req, resp, maxRatio) && s.verifyDigest {
+		verifyReprDigest(resp)
+	}
+}
+
+// limitBody limits the response Body to the Sling's MaxResponseBytes.
+func (s *Sling) limitBody(resp *http.Response) {
+	if s.maxBodyBytes > 0 {
+		resp.Body = &limitedReadCloser{rc: resp.Body, limit: s.maxBodyBytes}
+	}
+}
+
+// verifyBody reads the remainder of the response Body, if its digest is
+// verified, so that a digest mismatch is returned even if the Body wasn't
+// decoded to the end.
+func verifyBody(resp *http.Response, verifying bool) error {
+	if !verifying {
+		return nil
+	}
+	_, err := io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// DoContext is like Do, but sends the request with the given context.
+func (s *Sling) DoContext(ctx context.Context, req *http.Request, successV, failureV interface{}) (*http.Response, error) {
+	return s.Do(req.WithContext(ctx), successV, failureV)
 }
 
 // decodeResponse decodes response Body into the value pointed to by successV
@@ -409,7 +1114,7 @@ func (s *Sling) Do(req *http.Request, successV, failureV interface{}) (*http.Res
 // decoding is skipped.
 // Caller is responsible for closing the resp.Body.
 func decodeResponse(resp *http.Response, decoder ResponseDecoder, successV, failureV interface{}) error {
-	if code := resp.StatusCode; 200 <= code && code <= 299 {
+	if isSuccess(resp.StatusCode) {
 		if successV != nil {
 			return decoder.Decode(resp, successV)
 		}
@@ -420,3 +1125,8 @@ func decodeResponse(resp *http.Response, decoder ResponseDecoder, successV, fail
 	}
 	return nil
 }
+
+// isSuccess returns whether the status code is a success (2XX).
+func isSuccess(code int) bool {
+	return 200 <= code && code <= 299
+}
@@ -13,9 +13,12 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type FakeParams struct {
@@ -40,13 +43,6 @@ type FakeModel struct {
 
 var modelA = FakeModel{Text: "note", FavoriteCount: 12}
 
-// Non-Json response decoder
-type xmlResponseDecoder struct{}
-
-func (d xmlResponseDecoder) Decode(resp *http.Response, v interface{}) error {
-	return xml.NewDecoder(resp.Body).Decode(v)
-}
-
 func TestNew(t *testing.T) {
 	sling := New()
 	if sling.httpClient != http.DefaultClient {
@@ -116,6 +112,93 @@ func TestSlingNew(t *testing.T) {
 	}
 }
 
+func TestSlingNew_headerValues(t *testing.T) {
+	parent := New().Add("A", "1").Add("A", "2").Add("A", "3")
+	child1 := parent.New().Add("A", "4")
+	child2 := parent.New().Add("A", "5")
+	expected := []string{"1", "2", "3", "4"}
+	if !reflect.DeepEqual(expected, child1.header.Values("A")) {
+		t.Errorf("expected %v, got %v", expected, child1.header.Values("A"))
+	}
+	expected = []string{"1", "2", "3"}
+	if !reflect.DeepEqual(expected, parent.header.Values("A")) {
+		t.Errorf("expected %v, got %v", expected, parent.header.Values("A"))
+	}
+	if child2.header.Values("A")[3] != "5" {
+		t.Errorf("expected %s, got %s", "5", child2.header.Values("A")[3])
+	}
+}
+
+func TestImmutable(t *testing.T) {
+	params := &FakeParams{KindName: "a"}
+	base := New().Base("http://a.io/").Add("A", "1").Immutable()
+	child := base.Get("foo").Add("A", "2").QueryStruct(params).BodyJSON(modelA).ErrorOnFailure().MapStatusError(404, errors.New("not found"))
+	params.KindName = "b"
+
+	if base == child {
+		t.Fatalf("expected setters to return a copy")
+	}
+	if base.rawURL != "http://a.io/" || base.method != "GET" || len(base.header.Values("A")) != 1 {
+		t.Errorf("expected base to be unmodified, got %v %v %v", base.method, base.rawURL, base.header)
+	}
+	if len(base.queryStructs) != 0 || base.bodyProvider != nil || base.errorOnFailure || base.statusErrors != nil {
+		t.Errorf("expected base to be unmodified")
+	}
+	if !child.immutable {
+		t.Errorf("expected children of an immutable Sling to be immutable")
+	}
+	req, err := child.Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if expected := "http://a.io/foo?count=0&kind_name=a"; req.URL.String() != expected {
+		t.Errorf("expected %s, got %s", expected, req.URL.String())
+	}
+	if values := req.Header.Values("A"); !reflect.DeepEqual([]string{"1", "2"}, values) {
+		t.Errorf("expected %v, got %v", []string{"1", "2"}, values)
+	}
+	if req.Header.Get(contentType) != jsonContentType {
+		t.Errorf("expected %s, got %s", jsonContentType, req.Header.Get(contentType))
+	}
+
+	// mutable Slings are modified by setters
+	mutable := New()
+	if mutable.Get("foo") != mutable {
+		t.Errorf("expected setters to modify a mutable Sling")
+	}
+}
+
+func TestImmutable_concurrent(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"text": "%s"}`, r.Header.Get("X-Worker"))
+	})
+
+	base := New().Client(client).Base("http://example.com/").Set("Accept", "application/json").Immutable()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			worker := strconv.Itoa(i)
+			s := base.Get(worker).Set("X-Worker", worker).Add("Accept", "text/plain").QueryStruct(&FakeParams{KindName: worker})
+			model := new(FakeModel)
+			if _, err := s.ReceiveSuccess(model); err != nil {
+				t.Errorf("expected nil, got %v", err)
+			}
+			if model.Text != worker {
+				t.Errorf("expected %s, got %s", worker, model.Text)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if base.rawURL != "http://example.com/" || len(base.header) != 1 || len(base.queryStructs) != 0 {
+		t.Errorf("expected base to be unmodified, got %v %v %v", base.rawURL, base.header, base.queryStructs)
+	}
+}
+
 func TestClientSetter(t *testing.T) {
 	developerClient := &http.Client{}
 	cases := []struct {
@@ -194,6 +277,40 @@ func TestPathSetter(t *testing.T) {
 	}
 }
 
+func TestPathParams(t *testing.T) {
+	type repoParams struct {
+		Owner string `path:"owner"`
+		Repo  string `path:"repo"`
+	}
+	base := New().Base("http://a.io/").PathParams(map[string]string{"owner": "default"})
+	cases := []struct {
+		sling       *Sling
+		expectedURL string
+	}{
+		{base.New().Get("repos/{owner}/{repo}/issues{?state,labels}").PathParams(&repoParams{"a b", "x/y"}).PathParams(map[string]interface{}{"state": "open", "labels": []string{"bug", "ui"}}), "http://a.io/repos/a%20b/x%2Fy/issues?labels=bug%2Cui&state=open"},
+		{base.New().Get("repos/{owner}/").Path("issues").PathParams(map[string]string{"repo": "unused"}), "http://a.io/repos/default/issues"},
+		{base.New().Path("users{/user}/repos").PathParams(map[string]interface{}{"user": nil}), "http://a.io/users/repos"},
+		{base.New().Path("repos/{owner}/{repo}").PathParams(map[string]interface{}{"owner": []byte("a b"), "repo": [4]byte{'s', 'l', 'i', 'n'}}), "http://a.io/repos/a%20b/slin"},
+		{base.New().Get("repos/{owner}").QueryStruct(paramsA), "http://a.io/repos/default?limit=30"},
+		{base.New().Get("repos/{owner}").Base("http://b.io/"), "http://b.io/"},
+	}
+	for _, c := range cases {
+		req, err := c.sling.Request()
+		if err != nil {
+			t.Errorf("expected nil, got %v", err)
+			continue
+		}
+		if req.URL.String() != c.expectedURL {
+			t.Errorf("expected %s, got %s", c.expectedURL, req.URL.String())
+		}
+	}
+
+	_, err := base.New().Get("repos/{owner}/{repo}").Request()
+	if err == nil || err.Error() != `sling: missing path variable "repo"` {
+		t.Errorf("expected a missing path variable error, got %v", err)
+	}
+}
+
 func TestMethodSetters(t *testing.T) {
 	cases := []struct {
 		sling          *Sling
@@ -261,6 +378,27 @@ func TestSetHeader(t *testing.T) {
 	}
 }
 
+func TestDelHeader(t *testing.T) {
+	parent := New().Set("A", "B").Set("C", "D")
+	cases := []struct {
+		sling          *Sling
+		expectedHeader map[string][]string
+	}{
+		{New().Add("A", "B").Del("a"), map[string][]string{}},
+		// Del should delete values received by copying parent Slings
+		{parent.New().Del("A"), map[string][]string{"C": []string{"D"}}},
+		{parent.New().Del("A").Add("A", "E"), map[string][]string{"A": []string{"E"}, "C": []string{"D"}}},
+		{parent, map[string][]string{"A": []string{"B"}, "C": []string{"D"}}},
+	}
+	for _, c := range cases {
+		// type conversion from Header to alias'd map for deep equality comparison
+		headerMap := map[string][]string(c.sling.header)
+		if !reflect.DeepEqual(c.expectedHeader, headerMap) {
+			t.Errorf("not DeepEqual: expected %v, got %v", c.expectedHeader, headerMap)
+		}
+	}
+}
+
 func TestBasicAuth(t *testing.T) {
 	cases := []struct {
 		sling        *Sling
@@ -486,6 +624,7 @@ func TestRequest_body(t *testing.T) {
 		{New().BodyForm(paramsA), "limit=30", formContentType},
 		{New().BodyForm(paramsB), "count=25&kind_name=recent", formContentType},
 		{New().BodyForm(&paramsB), "count=25&kind_name=recent", formContentType},
+		{New().BodyForm(url.Values{"grant_type": {"client_credentials"}, "scope": {"a b"}}), "grant_type=client_credentials&scope=a+b", formContentType},
 		// BodyForm overrides existing values
 		{New().BodyForm(paramsA).New().BodyForm(paramsB), "count=25&kind_name=recent", formContentType},
 		// Mixture of BodyJSON and BodyForm prefers body setter called last with a non-nil argument
@@ -592,10 +731,16 @@ func TestAddQueryStructs(t *testing.T) {
 		{"http://a.io", []interface{}{paramsA, paramsB}, "http://a.io?count=25&kind_name=recent&limit=30"},
 		// don't blow away query values on the rawURL (parsed into RawQuery)
 		{"http://a.io?initial=7", []interface{}{paramsA}, "http://a.io?initial=7&limit=30"},
+		// query params, url.Values, maps, and QueryEncoders
+		{"http://a.io?initial=7", []interface{}{addQuery{"initial": {"8"}}}, "http://a.io?initial=7&initial=8"},
+		{"http://a.io?initial=7", []interface{}{paramsA, setQuery{"initial": {"8"}}}, "http://a.io?initial=8&limit=30"},
+		{"http://a.io?initial=7", []interface{}{delQuery{"initial"}, paramsA}, "http://a.io?limit=30"},
+		{"http://a.io", []interface{}{url.Values{"a": {"1", "2"}}, map[string]string{"b": "3"}}, "http://a.io?a=1&a=2&b=3"},
+		{"http://a.io", []interface{}{map[string][]string{"a": {"1"}}, fakeQueryEncoder{"c", "4"}}, "http://a.io?a=1&c=4"},
 	}
 	for _, c := range cases {
 		reqURL, _ := url.Parse(c.rawurl)
-		addQueryStructs(reqURL, c.queryStructs)
+		addQueryStructs(reqURL, c.queryStructs, ArrayRepeat)
 		if reqURL.String() != c.expected {
 			t.Errorf("expected %s, got %s", c.expected, reqURL.String())
 		}
@@ -775,7 +920,7 @@ func TestReceive_success_nonDefaultDecoder(t *testing.T) {
 
 	model := new(FakeModel)
 	apiError := new(APIError)
-	resp, err := endpoint.New().ResponseDecoder(xmlResponseDecoder{}).Receive(model, apiError)
+	resp, err := endpoint.New().ResponseDecoder(XMLDecoder{}).Receive(model, apiError)
 
 	if err != nil {
 		t.Errorf("expected nil, got %v", err)
@@ -924,6 +1069,137 @@ func TestReceive_errorCreatingRequest(t *testing.T) {
 	}
 }
 
+func TestContextSetter(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "v")
+	parent := New().Context(ctx)
+	child := parent.New().Get("http://a.io/")
+	req, err := child.Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if req.Context().Value(key{}) != "v" {
+		t.Errorf("expected request context to inherit the Sling context")
+	}
+	req, _ = New().Context(nil).Request()
+	if req.Context() != context.Background() {
+		t.Errorf("expected %v, got %v", context.Background(), req.Context())
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "override")
+	req, err := New().Context(context.TODO()).Get("http://a.io/").RequestContext(ctx)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if req.Context().Value(key{}) != "override" {
+		t.Errorf("expected RequestContext context to override the Sling context")
+	}
+}
+
+// blockingBodyProvider blocks encoding until the context is done.
+type blockingBodyProvider struct {
+	started chan struct{}
+}
+
+func (p blockingBodyProvider) ContentType() string {
+	return "text/plain"
+}
+
+func (p blockingBodyProvider) Body() (io.Reader, error) {
+	return strings.NewReader("body"), nil
+}
+
+func (p blockingBodyProvider) BodyContext(ctx context.Context) (io.Reader, error) {
+	close(p.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestContext_bodyProviderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	provider := blockingBodyProvider{started: make(chan struct{})}
+	go func() {
+		<-provider.started
+		cancel()
+	}()
+	_, err := New().Post("http://a.io/").BodyProvider(provider).RequestContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	// already done contexts skip body encoding
+	_, err = New().Post("http://a.io/").BodyJSON(modelA).RequestContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestReceiveContext_deadlineExceeded(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	model := new(FakeModel)
+	_, err := New().Client(client).Get("http://example.com/slow").ReceiveContext(ctx, model, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestReceiveContext_decodeCanceled(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	mux.HandleFunc("/partial", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"text": "partial`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	model := new(FakeModel)
+	_, err := New().Client(client).Context(ctx).Get("http://example.com/partial").ReceiveSuccess(model)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/success", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"text": "Some text"}`)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest("GET", "http://example.com/success", nil)
+	_, err := New().Client(client).DoContext(ctx, req, new(FakeModel), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
 func TestReuseTcpConnections(t *testing.T) {
 	var connCount int32
 
@@ -0,0 +1,180 @@
+package sling
+
+import (
+	"io"
+	"iter"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ReceiveStream creates a new HTTP request and returns the response with an
+// open Body for streaming, which the caller must close. Non-2XX responses
+// are decoded into the value pointed to by failureV (if non-nil), closed,
+// and returned with an *HTTPError.
+// ReceiveStream is shorthand for calling Request and DoStream.
+func (s *Sling) ReceiveStream(failureV interface{}) (*http.Response, error) {
+	req, err := s.Request()
+	if err != nil {
+		return nil, err
+	}
+	return s.DoStream(req, failureV)
+}
+
+// DoStream sends an HTTP request and returns the response with an open Body
+// for streaming, which the caller must close. Non-2XX responses are decoded
+// into the value pointed to by failureV (if non-nil), closed, and returned
+// with an *HTTPError. Gzip and deflate encoded Bodies are decompressed (see
+// MaxDecompressionRatio).
+func (s *Sling) DoStream(req *http.Request, failureV interface{}) (*http.Response, error) {
+	resp, _, err := s.doStream(req, failureV)
+	return resp, err
+}
+
+// doStream is like DoStream, but also returns whether the response Body's
+// digest is verified as it's read.
+func (s *Sling) doStream(req *http.Request, failureV interface{}) (*http.Response, bool, error) {
+	resp, attempts, err := s.send(req)
+	if err != nil {
+		return resp, false, err
+	}
+	verifying := s.wrapResponseBody(req, resp)
+	s.decompressBody(req, resp)
+	s.limitBody(resp)
+	if !isSuccess(resp.StatusCode) {
+		defer resp.Body.Close()
+		defer s.drain(resp.Body)
+		return resp, verifying, withAttempts(attempts, newHTTPError(req, resp, s.responseDecoder, failureV, s.statusErrors))
+	}
+	return resp, verifying, nil
+}
+
+// receiveStream sends the Sling's request with doStream.
+func (s *Sling) receiveStream(failureV interface{}) (*http.Response, bool, error) {
+	req, err := s.Request()
+	if err != nil {
+		return nil, false, err
+	}
+	return s.doStream(req, failureV)
+}
+
+// receiveSeq returns an iterator which sends the Sling's request and yields
+// the values decoded from a success (2XX) response by decode. Non-2XX
+// responses are decoded into the value pointed to by failureV (if non-nil)
+// and yielded as an *HTTPError. If the Body's digest is verified, the rest
+// of the Body is read once decoding completes, so a mismatch is yielded.
+// Errors are yielded last.
+func receiveSeq[T any](s *Sling, failureV interface{}, decode func(resp *http.Response, yield func(T) bool) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		resp, verifying, err := s.receiveStream(failureV)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer resp.Body.Close()
+		stopped := false
+		err = decode(resp, func(v T) bool {
+			stopped = !yield(v, nil)
+			return !stopped
+		})
+		if err == nil && !stopped {
+			err = verifyBody(resp, verifying)
+		}
+		if err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// receiveFunc sends the Sling's request and calls fn with the values decoded
+// from a success (2XX) response by decode, stopping if fn returns an error.
+// Non-2XX responses are decoded into the value pointed to by failureV (if
+// non-nil) and return an *HTTPError. If the Body's digest is verified, the
+// rest of the Body is read once decoding completes, so a mismatch is
+// returned.
+func receiveFunc[T any](s *Sling, failureV interface{}, fn func(T) error, decode func(resp *http.Response, yield func(T) bool) error) (*http.Response, error) {
+	resp, verifying, err := s.receiveStream(failureV)
+	if err != nil {
+		return resp, err
+	}
+	defer resp.Body.Close()
+	var fnErr error
+	err = decode(resp, func(v T) bool {
+		fnErr = fn(v)
+		return fnErr == nil
+	})
+	if fnErr != nil {
+		return resp, fnErr
+	}
+	if err != nil {
+		return resp, err
+	}
+	return resp, verifyBody(resp, verifying)
+}
+
+// ProgressFunc is called as a response Body is copied with the number of
+// bytes written so far and the total number of bytes expected (the response
+// Content-Length, or -1 if unknown).
+type ProgressFunc func(written, total int64)
+
+// CopyResponse copies the response Body to w and closes it, calling progress
+// (if non-nil) after each write. Returns the number of bytes copied.
+func CopyResponse(w io.Writer, resp *http.Response, progress ProgressFunc) (int64, error) {
+	defer resp.Body.Close()
+	if progress != nil {
+		w = &progressWriter{w: w, total: resp.ContentLength, progress: progress}
+	}
+	return io.Copy(w, resp.Body)
+}
+
+// SaveResponse copies the response Body to the file at path and closes it,
+// calling progress (if non-nil) after each write. The Body is written to a
+// temporary file in the same directory which is renamed to path once the
+// copy completes, so partial downloads never replace an existing file. The
+// file keeps the mode of the file it replaces, or is created with mode 0644.
+// Returns the number of bytes copied.
+func SaveResponse(path string, resp *http.Response, progress ProgressFunc) (n int64, err error) {
+	defer resp.Body.Close()
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	// temporary files are created with mode 0600
+	if err = file.Chmod(mode); err != nil {
+		return 0, err
+	}
+	n, err = CopyResponse(file, resp, progress)
+	if err != nil {
+		return n, err
+	}
+	if err = file.Close(); err != nil {
+		return n, err
+	}
+	return n, os.Rename(file.Name(), path)
+}
+
+// progressWriter reports the progress of writes.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.progress(p.written, p.total)
+	return n, err
+}
@@ -0,0 +1,129 @@
+package sling
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReceiveStream(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	content := strings.Repeat("artifact ", 10000)
+	mux.HandleFunc("/artifact", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("expected Sling headers to be sent")
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		fmt.Fprint(w, content)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(404)
+		fmt.Fprint(w, `{"message": "Not Found", "code": 404}`)
+	})
+
+	base := New().Client(client).Base("http://example.com/").SetBasicAuth("user", "pass")
+	resp, err := base.New().Get("artifact").ReceiveStream(nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	var buf bytes.Buffer
+	var last, total int64
+	n, err := CopyResponse(&buf, resp, func(written, size int64) {
+		if written < last {
+			t.Errorf("expected progress to increase, got %d after %d", written, last)
+		}
+		last, total = written, size
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if n != int64(len(content)) || buf.String() != content {
+		t.Errorf("expected %d bytes, got %d", len(content), n)
+	}
+	if last != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("expected final progress (%d, %d), got (%d, %d)", len(content), len(content), last, total)
+	}
+
+	apiError := new(APIError)
+	resp, err = base.New().Get("missing").ReceiveStream(apiError)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 404 {
+		t.Fatalf("expected a 404 *HTTPError, got %v", err)
+	}
+	if apiError.Message != "Not Found" || httpErr.Failure != apiError {
+		t.Errorf("expected failureV to be decoded, got %v", apiError)
+	}
+	if _, err := resp.Body.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected failure response Body to be closed")
+	}
+}
+
+func TestSaveResponse(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/artifact", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "artifact contents")
+	})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "artifact.tar")
+	resp, err := New().Client(client).Get("http://example.com/artifact").ReceiveStream(nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	n, err := SaveResponse(path, resp, nil)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if n != 17 || string(content) != "artifact contents" {
+		t.Errorf("expected artifact contents, got %d %q", n, content)
+	}
+	if runtime.GOOS != "windows" {
+		// new files are readable by others, and replaced files keep their mode
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+			t.Errorf("expected mode %v, got %v", os.FileMode(0644), info.Mode().Perm())
+		}
+		os.Chmod(path, 0640)
+		resp = &http.Response{Body: io.NopCloser(strings.NewReader("artifact contents")), ContentLength: 17}
+		if _, err := SaveResponse(path, resp, nil); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+			t.Errorf("expected mode %v, got %v", os.FileMode(0640), info.Mode().Perm())
+		}
+	}
+
+	// failed copies remove the temporary file and keep the existing file
+	errRead := errors.New("connection reset")
+	resp = &http.Response{Body: io.NopCloser(io.MultiReader(strings.NewReader("partial"), &errReader{errRead})), ContentLength: -1}
+	if _, err := SaveResponse(path, resp, nil); !errors.Is(err, errRead) {
+		t.Errorf("expected %v, got %v", errRead, err)
+	}
+	content, _ = os.ReadFile(path)
+	if string(content) != "artifact contents" {
+		t.Errorf("expected existing file to be kept, got %q", content)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected temporary file to be removed, got %v", entries)
+	}
+}
+
+// errReader is an io.Reader which fails with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,314 @@
+package sling
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// templateOperator holds the expansion behavior of an RFC 6570 expression
+// operator (see RFC 6570 Appendix A).
+type templateOperator struct {
+	first         string
+	sep           string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+var templateOperators = map[byte]templateOperator{
+	'+': {first: "", sep: ",", allowReserved: true},
+	'#': {first: "#", sep: ",", allowReserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+// isTemplate returns whether the path is a URI template.
+func isTemplate(path string) bool {
+	return strings.ContainsAny(path, "{}")
+}
+
+// expandTemplate expands the RFC 6570 URI template (levels 1-4) with
+// variables from the path params (see PathParams). Variables which are not
+// in the path params are an error, except in query expansions ("{?var}" and
+// "{&var}"), which omit them.
+func expandTemplate(template string, params []interface{}) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if template[start] == '}' || end < 0 {
+			return "", fmt.Errorf("sling: unmatched brace in URI template %q", template)
+		}
+		b.WriteString(template[:start])
+		if err := expandExpression(&b, template[start+1:start+end], params); err != nil {
+			return "", err
+		}
+		template = template[start+end+1:]
+	}
+	b.WriteString(template)
+	return b.String(), nil
+}
+
+// expandExpression expands the expression (without braces) into b.
+func expandExpression(b *strings.Builder, expression string, params []interface{}) error {
+	var op templateOperator
+	if expression != "" {
+		if o, ok := templateOperators[expression[0]]; ok {
+			op = o
+			expression = expression[1:]
+		} else if strings.IndexByte("=,!@|", expression[0]) >= 0 {
+			return fmt.Errorf("sling: unsupported URI template operator %q", expression[0])
+		}
+	}
+	if op.sep == "" {
+		op.sep = ","
+	}
+	first := true
+	for _, spec := range strings.Split(expression, ",") {
+		name, prefix, explode, err := parseVarSpec(spec)
+		if err != nil {
+			return err
+		}
+		param, ok := lookupPathParam(params, name)
+		if !ok {
+			if op.first == "?" || op.first == "&" {
+				continue
+			}
+			return fmt.Errorf("sling: missing path variable %q", name)
+		}
+		value := templateValue(param)
+		if value == nil {
+			// undefined values are omitted
+			continue
+		}
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+		switch value := value.(type) {
+		case string:
+			if op.named {
+				b.WriteString(name)
+				if value == "" {
+					b.WriteString(op.ifEmpty)
+					continue
+				}
+				b.WriteByte('=')
+			}
+			if prefix > 0 {
+				if runes := []rune(value); len(runes) > prefix {
+					value = string(runes[:prefix])
+				}
+			}
+			b.WriteString(encodeTemplateValue(value, op.allowReserved))
+		case []string:
+			if prefix > 0 {
+				return fmt.Errorf("sling: prefix modifier on list variable %q", name)
+			}
+			if explode {
+				for i, item := range value {
+					if i > 0 {
+						b.WriteString(op.sep)
+					}
+					writeTemplatePair(b, op, name, item, false)
+				}
+				continue
+			}
+			if op.named {
+				b.WriteString(name + "=")
+			}
+			for i, item := range value {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(encodeTemplateValue(item, op.allowReserved))
+			}
+		case [][2]string:
+			if prefix > 0 {
+				return fmt.Errorf("sling: prefix modifier on associative array variable %q", name)
+			}
+			if explode {
+				for i, pair := range value {
+					if i > 0 {
+						b.WriteString(op.sep)
+					}
+					writeTemplatePair(b, op, encodeTemplateValue(pair[0], op.allowReserved), pair[1], true)
+				}
+				continue
+			}
+			if op.named {
+				b.WriteString(name + "=")
+			}
+			for i, pair := range value {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(encodeTemplateValue(pair[0], op.allowReserved))
+				b.WriteByte(',')
+				b.WriteString(encodeTemplateValue(pair[1], op.allowReserved))
+			}
+		}
+	}
+	return nil
+}
+
+// writeTemplatePair writes an exploded list item or associative array pair.
+// Items of named expansions and pairs are written as name=value.
+func writeTemplatePair(b *strings.Builder, op templateOperator, name, value string, pair bool) {
+	if !op.named && !pair {
+		b.WriteString(encodeTemplateValue(value, op.allowReserved))
+		return
+	}
+	b.WriteString(name)
+	if value == "" && op.named {
+		b.WriteString(op.ifEmpty)
+		return
+	}
+	b.WriteByte('=')
+	b.WriteString(encodeTemplateValue(value, op.allowReserved))
+}
+
+// parseVarSpec parses a variable name with an optional prefix (":n") or
+// explode ("*") modifier.
+func parseVarSpec(spec string) (name string, prefix int, explode bool, err error) {
+	name = spec
+	if n, ok := strings.CutSuffix(spec, "*"); ok {
+		name, explode = n, true
+	} else if n, length, ok := strings.Cut(spec, ":"); ok {
+		name = n
+		prefix, err = strconv.Atoi(length)
+		if err != nil || prefix <= 0 || prefix >= 10000 {
+			return "", 0, false, fmt.Errorf("sling: invalid prefix modifier in URI template variable %q", spec)
+		}
+	}
+	if name == "" || strings.Trim(name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_.%") != "" {
+		return "", 0, false, fmt.Errorf("sling: invalid URI template variable %q", spec)
+	}
+	return name, prefix, explode, nil
+}
+
+// lookupPathParam returns the value of the named variable from the path
+// params, which may be maps with string keys or structs with fields tagged
+// "path". Later params take precedence.
+func lookupPathParam(params []interface{}, name string) (interface{}, bool) {
+	for i := len(params) - 1; i >= 0; i-- {
+		rv := reflect.ValueOf(params[i])
+		for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				continue
+			}
+			if value := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())); value.IsValid() {
+				return value.Interface(), true
+			}
+		case reflect.Struct:
+			rt := rv.Type()
+			for i := 0; i < rt.NumField(); i++ {
+				field := rt.Field(i)
+				if field.IsExported() && field.Tag.Get("path") == name {
+					return rv.Field(i).Interface(), true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// templateValue converts a variable value to a string, a list ([]string), or
+// an associative array ([][2]string, sorted by key). Nil values and empty
+// lists or associative arrays are undefined (nil).
+func templateValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if _, ok := rv.Interface().(fmt.Stringer); ok {
+		return fmt.Sprint(rv.Interface())
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// copy elements, since arrays may not be addressable
+			b := make([]byte, rv.Len())
+			for i := range b {
+				b[i] = byte(rv.Index(i).Uint())
+			}
+			return string(b)
+		}
+		list := make([]string, rv.Len())
+		for i := range list {
+			list[i] = fmt.Sprint(rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Map:
+		if rv.Len() == 0 {
+			return nil
+		}
+		values := make(map[string]string, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			values[fmt.Sprint(iter.Key().Interface())] = fmt.Sprint(iter.Value().Interface())
+		}
+		pairs := make([][2]string, 0, len(values))
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			pairs = append(pairs, [2]string{key, values[key]})
+		}
+		return pairs
+	}
+	return fmt.Sprint(rv.Interface())
+}
+
+// encodeTemplateValue percent-encodes the value, allowing unreserved
+// characters, and reserved characters and percent-encoded triplets if
+// allowReserved is true.
+func encodeTemplateValue(value string, allowReserved bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case isUnreserved(c):
+			b.WriteByte(c)
+		case allowReserved && strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0:
+			b.WriteByte(c)
+		case allowReserved && c == '%' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]):
+			b.WriteString(value[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// isUnreserved returns whether c is an RFC 3986 unreserved character.
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
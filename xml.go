@@ -0,0 +1,148 @@
+package sling
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// xmlBodyProvider encodes an XML tagged struct value as a Body for requests.
+// See https://golang.org/pkg/encoding/xml/#Marshal for details.
+type xmlBodyProvider struct {
+	payload interface{}
+}
+
+func (p xmlBodyProvider) ContentType() string {
+	return xmlContentType
+}
+
+func (p xmlBodyProvider) Body() (io.Reader, error) {
+	buf := bytes.NewBufferString(xml.Header)
+	err := xml.NewEncoder(buf).Encode(p.payload)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// XMLDecoder decodes http response XML into an XML-tagged struct value.
+//
+// With SOAP set, responses are SOAP 1.1 or 1.2 envelopes and the first
+// element inside the Envelope Body is decoded. Faults in failure (non-2XX)
+// responses are decoded into the failure value (e.g. a *SOAPFault), while
+// faults in success responses are returned as a *SOAPFault error.
+type XMLDecoder struct {
+	// SOAP decodes the payload inside SOAP Envelope Bodies.
+	SOAP bool
+}
+
+// Decode decodes the Response Body into the value pointed to by v.
+// Caller must provide a non-nil v and close the resp.Body.
+func (d XMLDecoder) Decode(resp *http.Response, v interface{}) error {
+	decoder := xml.NewDecoder(resp.Body)
+	if !d.SOAP {
+		return decoder.Decode(v)
+	}
+	payload, err := soapPayload(decoder)
+	if err != nil {
+		return err
+	}
+	if payload.Name.Local == "Fault" && isSuccess(resp.StatusCode) {
+		fault := new(SOAPFault)
+		if err := decoder.DecodeElement(fault, payload); err != nil {
+			return err
+		}
+		return fault
+	}
+	return decoder.DecodeElement(v, payload)
+}
+
+// soapPayload advances the decoder to the first element inside the Body of
+// a SOAP Envelope.
+func soapPayload(decoder *xml.Decoder) (*xml.StartElement, error) {
+	// element names expected at each depth
+	path := []string{"Envelope", "Body"}
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("sling: SOAP %s not found", strings.Join(path[:depth+1], "/"))
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == len(path) {
+				return &t, nil
+			}
+			if t.Name.Local != path[depth] {
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			return nil, fmt.Errorf("sling: SOAP %s is empty", strings.Join(path[:depth], "/"))
+		}
+	}
+}
+
+// SOAPFault is a SOAP 1.1 or 1.2 Fault.
+type SOAPFault struct {
+	// Code is the faultcode (SOAP 1.1) or Code Value (SOAP 1.2).
+	Code string
+	// String is the faultstring (SOAP 1.1) or Reason Text (SOAP 1.2).
+	String string
+	// Actor is the faultactor (SOAP 1.1) or Role (SOAP 1.2).
+	Actor string
+	// Detail is the raw inner XML of the fault detail.
+	Detail string
+}
+
+func (f *SOAPFault) Error() string {
+	return fmt.Sprintf("sling: SOAP fault %s: %s", f.Code, f.String)
+}
+
+// UnmarshalXML decodes SOAP 1.1 and 1.2 Fault elements.
+func (f *SOAPFault) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var fault struct {
+		// SOAP 1.1
+		FaultCode   string `xml:"faultcode"`
+		FaultString string `xml:"faultstring"`
+		FaultActor  string `xml:"faultactor"`
+		FaultDetail struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"detail"`
+		// SOAP 1.2
+		Code   string `xml:"Code>Value"`
+		Reason string `xml:"Reason>Text"`
+		Role   string `xml:"Role"`
+		Detail struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"Detail"`
+	}
+	if err := d.DecodeElement(&fault, &start); err != nil {
+		return err
+	}
+	*f = SOAPFault{
+		Code:   firstNonEmpty(fault.FaultCode, fault.Code),
+		String: firstNonEmpty(fault.FaultString, fault.Reason),
+		Actor:  firstNonEmpty(fault.FaultActor, fault.Role),
+		Detail: firstNonEmpty(fault.FaultDetail.Inner, fault.Detail.Inner),
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package sling
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBodyXMLSetter(t *testing.T) {
+	req, err := New().Post("http://example.com/").BodyXML(&FakeModel{Text: "note"}).Request()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if ct := req.Header.Get(contentType); ct != xmlContentType {
+		t.Errorf("expected %s, got %s", xmlContentType, ct)
+	}
+	body, _ := io.ReadAll(req.Body)
+	expected := xml.Header + `<FakeModel><text>note</text><favorite_count>0</favorite_count><temperature>0</temperature></FakeModel>`
+	if string(body) != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+
+	// nil bodyXML is ignored
+	if s := New().BodyXML(nil); s.bodyProvider != nil {
+		t.Errorf("expected nil bodyProvider, got %v", s.bodyProvider)
+	}
+}
+
+const soapEnvelope = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Header><auth>token</auth></soap:Header>
+  <soap:Body>%s</soap:Body>
+</soap:Envelope>`
+
+func TestXMLDecoder_soap(t *testing.T) {
+	client, mux, server := testServer()
+	defer server.Close()
+	mux.HandleFunc("/soap", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		switch r.URL.Query().Get("case") {
+		case "success":
+			fmt.Fprintf(w, soapEnvelope, `<m:GetPriceResponse xmlns:m="urn:x"><text>Some text</text><favorite_count>24</favorite_count></m:GetPriceResponse>`)
+		case "fault11":
+			w.WriteHeader(500)
+			fmt.Fprintf(w, soapEnvelope, `<soap:Fault><faultcode>soap:Client</faultcode><faultstring>Invalid price</faultstring><detail><code>42</code></detail></soap:Fault>`)
+		case "fault12":
+			w.WriteHeader(500)
+			fmt.Fprint(w, `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>`+
+				`<env:Code><env:Value>env:Sender</env:Value></env:Code><env:Reason><env:Text xml:lang="en">Invalid price</env:Text></env:Reason>`+
+				`</env:Fault></env:Body></env:Envelope>`)
+		case "faultOK":
+			fmt.Fprintf(w, soapEnvelope, `<soap:Fault><faultcode>soap:Server</faultcode><faultstring>Unavailable</faultstring></soap:Fault>`)
+		case "empty":
+			fmt.Fprintf(w, soapEnvelope, ``)
+		}
+	})
+
+	s := New().Client(client).Get("http://example.com/soap").ResponseDecoder(XMLDecoder{SOAP: true})
+	model := new(FakeModel)
+	fault := new(SOAPFault)
+	_, err := s.New().QueryStruct(setQuery{"case": {"success"}}).Receive(model, fault)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if expected := (&FakeModel{Text: "Some text", FavoriteCount: 24}); !reflect.DeepEqual(expected, model) {
+		t.Errorf("expected %v, got %v", expected, model)
+	}
+
+	_, err = s.New().QueryStruct(setQuery{"case": {"fault11"}}).Receive(model, fault)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	expectedFault := &SOAPFault{Code: "soap:Client", String: "Invalid price", Detail: "<code>42</code>"}
+	if !reflect.DeepEqual(expectedFault, fault) {
+		t.Errorf("expected %v, got %v", expectedFault, fault)
+	}
+
+	fault = new(SOAPFault)
+	_, err = s.New().QueryStruct(setQuery{"case": {"fault12"}}).Receive(model, fault)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	expectedFault = &SOAPFault{Code: "env:Sender", String: "Invalid price"}
+	if !reflect.DeepEqual(expectedFault, fault) {
+		t.Errorf("expected %v, got %v", expectedFault, fault)
+	}
+
+	_, err = s.New().QueryStruct(setQuery{"case": {"faultOK"}}).Receive(model, nil)
+	var soapFault *SOAPFault
+	if !errors.As(err, &soapFault) || soapFault.String != "Unavailable" {
+		t.Errorf("expected a *SOAPFault error, got %v", err)
+	}
+
+	_, err = s.New().QueryStruct(setQuery{"case": {"empty"}}).Receive(model, nil)
+	if err == nil || !strings.Contains(err.Error(), "Envelope/Body is empty") {
+		t.Errorf("expected an empty Body error, got %v", err)
+	}
+}